@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const ErrAcharyaBadEntity = "expected an entity as [begin, end, \"Name\"] received %s"
+
+// AcharyaRecord is one line of the JSONL files written by the converter
+type AcharyaRecord struct {
+	Data     string
+	Entities []AcharyaEntity
+	Meta     *RecordMeta `json:",omitempty"`
+}
+
+// UnmarshalJSON reads the `[begin, end, "Name"]` arrays the converter writes
+func (e *AcharyaEntity) UnmarshalJSON(data []byte) error {
+	fields := []json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) != 3 {
+		return fmt.Errorf(ErrAcharyaBadEntity, data)
+	}
+	if json.Unmarshal(fields[0], &e.Begin) != nil || json.Unmarshal(fields[1], &e.End) != nil || json.Unmarshal(fields[2], &e.Name) != nil {
+		return fmt.Errorf(ErrAcharyaBadEntity, data)
+	}
+	return nil
+}
+
+// MarshalJSON writes the entity as the `[begin, end, "Name"]` array UnmarshalJSON reads
+func (e AcharyaEntity) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.Begin, e.End, e.Name})
+}
+
+// ReadAcharya reads the records of an Acharya JSONL file, empty lines are skipped
+func ReadAcharya(r io.Reader) ([]AcharyaRecord, error) {
+	records := []AcharyaRecord{}
+	reader := bufio.NewReader(r)
+	lineNo := 0
+
+	for {
+		// The records hold whole documents, they can be longer than a bufio.Scanner line
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			lineNo++
+		}
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			record := AcharyaRecord{}
+			if uErr := json.Unmarshal(trimmed, &record); uErr != nil {
+				return nil, &ParseError{Path: readerName(r), Line: lineNo, Raw: string(trimmed), Kind: ErrParseBadFormat, Err: uErr}
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
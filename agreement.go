@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"unicode"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	ErrAgreementRoots          = "the agreement command needs at least two collections, received %d"
+	ErrAgreementUnknownMatch   = "unknown match mode: %s, expected `exact`, `overlap` or `token`"
+	ErrAgreementUnknownFormat  = "unknown agreement format: %s, expected `table` or `json`"
+	ErrAgreementNoSharedDocs   = "no document is annotated in every collection"
+	ErrAgreementTextMismatched = "the text of %s differs between %s and %s"
+
+	MatchExact   = "exact"
+	MatchOverlap = "overlap"
+	MatchToken   = "token"
+
+	// outsideLabel is the label of the tokens that are not covered by any entity
+	outsideLabel = "O"
+)
+
+// Span returns the single span covering the text-bound annotation
+func (t TextBound) Span() Span {
+	return Span{t.Begin(), t.End()}
+}
+
+func (s Span) Overlaps(other Span) bool {
+	return s.Begin < other.End && other.Begin < s.End
+}
+
+// TokenSpans returns the whitespace separated tokens of the text, counted in characters the same way
+// as the brat offsets
+func TokenSpans(text string) []Span {
+	tokens := []Span{}
+	begin := -1
+	counter := 0
+	for _, r := range text {
+		if r == '\r' {
+			continue
+		}
+		if unicode.IsSpace(r) {
+			if begin != -1 {
+				tokens = append(tokens, Span{begin, counter})
+				begin = -1
+			}
+		} else if begin == -1 {
+			begin = counter
+		}
+		counter++
+	}
+	if begin != -1 {
+		tokens = append(tokens, Span{begin, counter})
+	}
+	return tokens
+}
+
+// TypeAgreement counts the annotations of one type the second annotator agrees on with the first one
+type TypeAgreement struct {
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+func (t *TypeAgreement) add(other TypeAgreement) {
+	t.TruePositives += other.TruePositives
+	t.FalsePositives += other.FalsePositives
+	t.FalseNegatives += other.FalseNegatives
+}
+
+func (t *TypeAgreement) score() {
+	t.Precision, t.Recall, t.F1 = 0, 0, 0
+	if t.TruePositives+t.FalsePositives > 0 {
+		t.Precision = float64(t.TruePositives) / float64(t.TruePositives+t.FalsePositives)
+	}
+	if t.TruePositives+t.FalseNegatives > 0 {
+		t.Recall = float64(t.TruePositives) / float64(t.TruePositives+t.FalseNegatives)
+	}
+	if t.Precision+t.Recall > 0 {
+		t.F1 = 2 * t.Precision * t.Recall / (t.Precision + t.Recall)
+	}
+}
+
+// PairAgreement compares the annotations of B against the ones of A, taken as reference
+type PairAgreement struct {
+	A           string                    `json:"a"`
+	B           string                    `json:"b"`
+	Types       map[string]*TypeAgreement `json:"types"`
+	Overall     TypeAgreement             `json:"overall"`
+	CohensKappa float64                   `json:"cohens_kappa"`
+}
+
+// AgreementReport is the output of the `agreement` command. Kappa is computed on the token labels.
+type AgreementReport struct {
+	Match       string          `json:"match"`
+	Collections []string        `json:"collections"`
+	Documents   int             `json:"documents"`
+	Missing     []string        `json:"missing"`
+	Pairs       []PairAgreement `json:"pairs"`
+	FleissKappa float64         `json:"fleiss_kappa"`
+}
+
+// agreementDocument is the text and entities of one document, as annotated in each collection
+type agreementDocument struct {
+	Name     string
+	Text     string
+	Entities [][]TextBound
+}
+
+// ComputeAgreement pairs the documents of the collections by their path relative to the collection root
+// and measures how much the annotators agree on the entities. The token labels are split by tokenizer.
+func ComputeAgreement(roots []string, collections []*Collection, match, tokenizer string) (*AgreementReport, error) {
+	if len(collections) < 2 {
+		return nil, fmt.Errorf(ErrAgreementRoots, len(collections))
+	}
+	switch match {
+	case MatchExact, MatchOverlap, MatchToken:
+	default:
+		return nil, fmt.Errorf(ErrAgreementUnknownMatch, match)
+	}
+
+	report := &AgreementReport{Match: match, Collections: roots, Missing: []string{}, Pairs: []PairAgreement{}}
+	docs, err := pairDocuments(roots, collections, report)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, errors.New(ErrAgreementNoSharedDocs)
+	}
+	report.Documents = len(docs)
+
+	// Every annotator labels every token, that is what kappa is computed on
+	labels := make([][]string, len(collections))
+	for _, doc := range docs {
+		tokens := Tokenize(doc.Text, tokenizer)
+		for i, entities := range doc.Entities {
+			labels[i] = append(labels[i], TokenLabels(tokens, entities)...)
+		}
+	}
+
+	for a := 0; a < len(collections); a++ {
+		for b := a + 1; b < len(collections); b++ {
+			pair := PairAgreement{A: roots[a], B: roots[b], Types: make(map[string]*TypeAgreement)}
+			if match == MatchToken {
+				compareLabels(labels[a], labels[b], pair.Types)
+			} else {
+				for _, doc := range docs {
+					compareEntities(doc.Entities[a], doc.Entities[b], match, pair.Types)
+				}
+			}
+			for _, counts := range pair.Types {
+				counts.score()
+				pair.Overall.add(*counts)
+			}
+			pair.Overall.score()
+			pair.CohensKappa = CohensKappa(labels[a], labels[b])
+			report.Pairs = append(report.Pairs, pair)
+		}
+	}
+	report.FleissKappa = FleissKappa(labels)
+	return report, nil
+}
+
+func pairDocuments(roots []string, collections []*Collection, report *AgreementReport) ([]*agreementDocument, error) {
+	// index maps the document name, relative to the collection root, to its position in the collection
+	indexes := make([]map[string]int, len(collections))
+	for i, collection := range collections {
+		indexes[i] = make(map[string]int)
+		for j, name := range collection.DocumentNames(roots[i]) {
+			indexes[i][name] = j
+		}
+	}
+
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, index := range indexes {
+		for name := range index {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	docs := []*agreementDocument{}
+	for _, name := range names {
+		doc := &agreementDocument{Name: name}
+		for i, collection := range collections {
+			j, ok := indexes[i][name]
+			if !ok {
+				report.Missing = append(report.Missing, name)
+				doc = nil
+				break
+			}
+
+			text, err := collection.ReadFile(collection.Txt[j])
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				doc.Text = string(text)
+			} else if string(text) != doc.Text {
+				return nil, fmt.Errorf(ErrAgreementTextMismatched, name, roots[0], roots[i])
+			}
+
+			entities, err := collection.ReadEntities(collection.Ann[j])
+			if err != nil {
+				return nil, err
+			}
+			doc.Entities = append(doc.Entities, entities)
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// ReadEntities returns the text-bound annotations of the document whose type is in `[entities]`, all of them when
+// no `annotation.conf` applies to the document
+func (c *Collection) ReadEntities(annPath string) ([]TextBound, error) {
+	_, entityTypes, err := c.DocumentTypes(annPath, false)
+	if err != nil {
+		return nil, err
+	}
+	standoff, err := c.ReadStandoff(annPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := []TextBound{}
+	for _, tb := range standoff.TextBounds {
+		if entityTypes == nil || entityTypes[tb.Type] {
+			entities = append(entities, tb)
+		}
+	}
+	return entities, nil
+}
+
+// compareEntities matches every entity of b with at most one entity of a of the same type
+func compareEntities(a, b []TextBound, match string, types map[string]*TypeAgreement) {
+	counts := func(name string) *TypeAgreement {
+		if _, ok := types[name]; !ok {
+			types[name] = &TypeAgreement{}
+		}
+		return types[name]
+	}
+
+	matched := make([]bool, len(a))
+	for _, entB := range b {
+		found := false
+		for i, entA := range a {
+			if matched[i] || entA.Type != entB.Type {
+				continue
+			}
+			if entA.Span() == entB.Span() || (match == MatchOverlap && entA.Span().Overlaps(entB.Span())) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			counts(entB.Type).TruePositives++
+		} else {
+			counts(entB.Type).FalsePositives++
+		}
+	}
+	for i, entA := range a {
+		if !matched[i] {
+			counts(entA.Type).FalseNegatives++
+		}
+	}
+}
+
+// TokenLabels returns the type of the entity covering every token, or `O`. The first entity wins
+// when several of them cover the same token.
+func TokenLabels(tokens []Span, entities []TextBound) []string {
+	labels := make([]string, len(tokens))
+	for i, token := range tokens {
+		labels[i] = outsideLabel
+		for _, ent := range entities {
+			if ent.Span().Overlaps(token) {
+				labels[i] = ent.Type
+				break
+			}
+		}
+	}
+	return labels
+}
+
+func compareLabels(a, b []string, types map[string]*TypeAgreement) {
+	counts := func(name string) *TypeAgreement {
+		if _, ok := types[name]; !ok {
+			types[name] = &TypeAgreement{}
+		}
+		return types[name]
+	}
+
+	for i := range a {
+		switch {
+		case a[i] == b[i] && a[i] != outsideLabel:
+			counts(a[i]).TruePositives++
+		case a[i] != b[i]:
+			if b[i] != outsideLabel {
+				counts(b[i]).FalsePositives++
+			}
+			if a[i] != outsideLabel {
+				counts(a[i]).FalseNegatives++
+			}
+		}
+	}
+}
+
+// CohensKappa is the chance corrected agreement of two annotators labelling the same items
+func CohensKappa(a, b []string) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	observed := 0.0
+	countsA := make(map[string]int)
+	countsB := make(map[string]int)
+	for i := range a {
+		if a[i] == b[i] {
+			observed++
+		}
+		countsA[a[i]]++
+		countsB[b[i]]++
+	}
+	n := float64(len(a))
+	observed /= n
+
+	expected := 0.0
+	for label, count := range countsA {
+		expected += float64(count) / n * float64(countsB[label]) / n
+	}
+	if expected == 1 {
+		return 1
+	}
+	return (observed - expected) / (1 - expected)
+}
+
+// FleissKappa is the chance corrected agreement of any number of annotators labelling the same items
+func FleissKappa(labels [][]string) float64 {
+	if len(labels) < 2 || len(labels[0]) == 0 {
+		return 0
+	}
+	raters := float64(len(labels))
+	items := len(labels[0])
+
+	agreement := 0.0
+	totals := make(map[string]int)
+	for item := 0; item < items; item++ {
+		counts := make(map[string]int)
+		for _, rater := range labels {
+			counts[rater[item]]++
+			totals[rater[item]]++
+		}
+		for _, count := range counts {
+			agreement += float64(count * (count - 1))
+		}
+	}
+	observed := agreement / (float64(items) * raters * (raters - 1))
+
+	expected := 0.0
+	for _, count := range totals {
+		p := float64(count) / (float64(items) * raters)
+		expected += p * p
+	}
+	if expected == 1 {
+		return 1
+	}
+	return (observed - expected) / (1 - expected)
+}
+
+// WriteTable prints the agreement of every pair of collections as human readable tables
+func (r *AgreementReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Documents\t%d\nMissing from a collection\t%d\nMatch\t%s\n", r.Documents, len(r.Missing), r.Match)
+	for _, pair := range r.Pairs {
+		fmt.Fprintf(tw, "\n%s vs %s\n", pair.A, pair.B)
+		fmt.Fprintf(tw, "  Type\tTP\tFP\tFN\tPrecision\tRecall\tF1\n")
+		names := make([]string, 0, len(pair.Types))
+		for name := range pair.Types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeTypeAgreement(tw, name, *pair.Types[name])
+		}
+		writeTypeAgreement(tw, "overall", pair.Overall)
+		fmt.Fprintf(tw, "  Cohen's kappa\t%.3f\n", pair.CohensKappa)
+	}
+	fmt.Fprintf(tw, "\nFleiss' kappa\t%.3f\n", r.FleissKappa)
+	return tw.Flush()
+}
+
+func writeTypeAgreement(w io.Writer, name string, t TypeAgreement) {
+	fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%.3f\t%.3f\t%.3f\n", name, t.TruePositives, t.FalsePositives, t.FalseNegatives, t.Precision, t.Recall, t.F1)
+}
+
+// runAgreement implements the `agreement` command
+func runAgreement(args []string) error {
+	flags := flag.NewFlagSet("agreement", flag.ContinueOnError)
+	include := flags.StringArrayP("include", "i", []string{}, "Glob pattern of the annotation files (.ann) to compare, relative to every collection. Can be repeated")
+	exclude := flags.StringArrayP("exclude", "x", []string{}, "Glob pattern of the annotation files (.ann) to skip. Can be repeated")
+	match := flags.String("match", MatchExact, "How entities are matched: exact (same span), overlap (overlapping spans) or token (token labels)")
+	tokenizer := flags.String("tokenizer", "", "Tokenizer of --match token: whitespace or ptblike. Defaults to the one of tools.conf, or whitespace")
+	format := flags.String("format", "table", "Output format: table or json")
+	oFileName := flags.StringP("output", "o", "", "Name of the output file to be generated")
+	overWrite := flags.BoolP("force", "f", false, "If you wish to overwrite the generated file then set force to true")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	roots := flags.Args()
+	if len(roots) < 2 {
+		return fmt.Errorf(ErrAgreementRoots, len(roots))
+	}
+	collections := []*Collection{}
+	for _, root := range roots {
+		collection, err := OpenCollection(Options{FolderPath: root, Include: *include, Exclude: *exclude})
+		if err != nil {
+			return err
+		}
+		collections = append(collections, collection)
+	}
+
+	// The tools.conf of the first collection is the one of the project
+	tools, err := collections[0].ToolsConf("")
+	if err != nil {
+		return err
+	}
+	resolvedTokenizer, _ := tools.Resolve(*tokenizer, "")
+	if err := ValidateTokenizer(resolvedTokenizer); err != nil {
+		return err
+	}
+
+	report, err := ComputeAgreement(roots, collections, *match, resolvedTokenizer)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	switch *format {
+	case "table":
+		err = report.WriteTable(buf)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(report, "", "  ")
+		buf.Write(append(data, '\n'))
+	default:
+		err = fmt.Errorf(ErrAgreementUnknownFormat, *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*oFileName, buf.String(), defaultOutputMode(*overWrite))
+}
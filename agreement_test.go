@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AgreementSuite struct {
+	suite.Suite
+}
+
+func (suite *AgreementSuite) TestTokenSpans() {
+	suite.Equal([]Span{{0, 3}, {4, 10}, {13, 16}}, TokenSpans("The merger\r\n  ok."))
+	suite.Equal([]Span{{0, 2}, {3, 9}}, TokenSpans("Öl Straße"))
+}
+
+func (suite *AgreementSuite) TestCompareEntities() {
+	a := []TextBound{
+		{ID: "T1", Type: "Person", Spans: []Span{{0, 5}}},
+		{ID: "T2", Type: "GPE", Spans: []Span{{10, 15}}},
+		{ID: "T3", Type: "GPE", Spans: []Span{{20, 25}}},
+	}
+	b := []TextBound{
+		{ID: "T7", Type: "Person", Spans: []Span{{0, 5}}},
+		{ID: "T8", Type: "GPE", Spans: []Span{{11, 15}}},
+		{ID: "T9", Type: "Person", Spans: []Span{{20, 25}}},
+	}
+
+	exact := make(map[string]*TypeAgreement)
+	compareEntities(a, b, MatchExact, exact)
+	suite.Equal(TypeAgreement{TruePositives: 1, FalsePositives: 1}, *exact["Person"])
+	suite.Equal(TypeAgreement{FalsePositives: 1, FalseNegatives: 2}, *exact["GPE"])
+
+	overlap := make(map[string]*TypeAgreement)
+	compareEntities(a, b, MatchOverlap, overlap)
+	suite.Equal(TypeAgreement{TruePositives: 1, FalseNegatives: 1}, *overlap["GPE"])
+
+	overlap["GPE"].score()
+	suite.Equal(1.0, overlap["GPE"].Precision)
+	suite.Equal(0.5, overlap["GPE"].Recall)
+	suite.InDelta(0.667, overlap["GPE"].F1, 0.001)
+}
+
+func (suite *AgreementSuite) TestKappa() {
+	suite.Equal(0.5, CohensKappa([]string{"A", "A", "B", "B"}, []string{"A", "B", "B", "B"}))
+	suite.Equal(1.0, CohensKappa([]string{"O", "O"}, []string{"O", "O"}))
+	suite.InDelta(-0.2, FleissKappa([][]string{{"X", "X"}, {"X", "X"}, {"X", "Y"}}), 0.0001)
+	suite.Equal(1.0, FleissKappa([][]string{{"A", "B"}, {"A", "B"}, {"A", "B"}}))
+}
+
+func (suite *AgreementSuite) TestComputeAgreement() {
+	dir, err := ioutil.TempDir("", "agreement")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	// The second annotator retyped the money, added a person and missed the other entities
+	for _, name := range []string{"annotation.conf", "000-introduction.txt"} {
+		data, err := ioutil.ReadFile(filepath.Join("testData/news", name))
+		suite.Nil(err)
+		suite.Nil(ioutil.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+	ann := "T1\tOrganization 418 426\tCitibank\nT2\tGPE 456 468\t$100 million\nT4\tPerson 559 566\tMichael\n"
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "000-introduction.ann"), []byte(ann), 0600))
+
+	roots := []string{"testData/news", dir}
+	collections := []*Collection{}
+	for _, root := range roots {
+		collection, err := OpenCollection(Options{FolderPath: root, Include: []string{"000-*"}})
+		suite.Nil(err)
+		collections = append(collections, collection)
+	}
+
+	report, err := ComputeAgreement(roots, collections, MatchExact, TokenizerWhitespace)
+	suite.Nil(err)
+	suite.Equal(1, report.Documents)
+	suite.Len(report.Pairs, 1)
+	suite.Equal(TypeAgreement{TruePositives: 1, FalsePositives: 2, FalseNegatives: 5, Precision: 1.0 / 3, Recall: 1.0 / 6, F1: 2.0 / 9}, report.Pairs[0].Overall)
+	suite.Equal(1, report.Pairs[0].Types["GPE"].FalsePositives)
+	suite.True(report.Pairs[0].CohensKappa < 1)
+
+	buf := &bytes.Buffer{}
+	suite.Nil(report.WriteTable(buf))
+	suite.Contains(buf.String(), "Cohen's kappa")
+
+	_, err = ComputeAgreement(roots[:1], collections[:1], MatchExact, TokenizerWhitespace)
+	suite.EqualError(err, "the agreement command needs at least two collections, received 1")
+	_, err = ComputeAgreement(roots, collections, "fuzzy", TokenizerWhitespace)
+	suite.EqualError(err, "unknown match mode: fuzzy, expected `exact`, `overlap` or `token`")
+}
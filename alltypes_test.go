@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AllTypesSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *AllTypesSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "all-types")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *AllTypesSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *AllTypesSuite) TestGenNumberEntityArrAllTypes() {
+	ann := "T1\tPerson 0 4\tJohn\nT2\tVehicle 10 13\tcar\nR1\tOwns Arg1:T1 Arg2:T2\t\n"
+	entities, err := GenNumberEntityArr(nil, strings.NewReader(ann))
+	suite.Nil(err)
+	suite.Equal([]NumberAcharyaEntity{{1, AcharyaEntity{0, 4, "Person"}}, {2, AcharyaEntity{10, 13, "Vehicle"}}}, entities)
+
+	entities, err = GenNumberEntityArr(map[string]bool{"Person": true}, strings.NewReader(ann))
+	suite.Nil(err)
+	suite.Equal([]NumberAcharyaEntity{{1, AcharyaEntity{0, 4, "Person"}}}, entities)
+}
+
+func (suite *AllTypesSuite) TestWithoutConf() {
+	for _, name := range []string{"000-introduction.txt", "000-introduction.ann"} {
+		data, err := ioutil.ReadFile(filepath.Join("testData/news", name))
+		suite.Nil(err)
+		suite.Nil(ioutil.WriteFile(filepath.Join(suite.TmpDir, name), data, 0600))
+	}
+
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	report := filepath.Join(suite.TmpDir, "report.json")
+	suite.Nil(handleMain(Options{FolderPath: suite.TmpDir, OutputFile: output, ErrorReport: report}))
+
+	records, err := ReadAcharya(mustOpen(suite, output))
+	suite.Nil(err)
+	suite.Len(records, 1)
+	suite.Len(records[0].Entities, 7)
+	suite.Nil(records[0].Meta)
+
+	data, err := ioutil.ReadFile(report)
+	suite.Nil(err)
+	errorReport := &ErrorReport{}
+	suite.Nil(json.Unmarshal(data, errorReport))
+	suite.Equal(map[string]int{"GPE": 1, "Money": 1, "Organization": 2, "Person": 2, "Transfer-money": 1}, errorReport.Types)
+}
+
+func (suite *AllTypesSuite) TestAllTypesFlag() {
+	opts := Options{
+		AnnFiles:   "./testData/news/000-introduction.ann",
+		ConfFile:   "./testData/invalid-files/no-entities/annotation.conf",
+		OutputFile: filepath.Join(suite.TmpDir, "out.jsonl"),
+	}
+	suite.EqualError(handleMain(opts), "./testData/invalid-files/no-entities/annotation.conf:10: "+ErrNoEntities)
+
+	opts.AllTypes = true
+	suite.Nil(handleMain(opts))
+
+	records, err := ReadAcharya(mustOpen(suite, opts.OutputFile))
+	suite.Nil(err)
+	suite.Len(records[0].Entities, 7)
+	suite.Equal("./testData/invalid-files/no-entities/annotation.conf", records[0].Meta.Conf)
+}
+
+func mustOpen(suite *AllTypesSuite, name string) *os.File {
+	f, err := os.Open(name)
+	suite.Require().Nil(err)
+	return f
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ErrArchiveUnsupported  = "unsupported archive format: %s"
+	ErrArchiveFileNotFound = "%s file does not exist in the archive"
+)
+
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// Archive is a brat collection read into memory from a .zip, .tar or .tar.gz file
+type Archive struct {
+	Path  string
+	Files map[string][]byte
+}
+
+// namedReader lets the parsers report the archive entry a document came from
+type namedReader struct {
+	*bytes.Reader
+	name string
+}
+
+func (r namedReader) Name() string {
+	return r.name
+}
+
+func (r namedReader) Close() error {
+	return nil
+}
+
+func IsArchive(path string) bool {
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(strings.ToLower(path), suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenArchive reads every regular file of the archive at path into memory
+func OpenArchive(path string) (*Archive, error) {
+	lowerPath := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lowerPath, ".zip"):
+		return readZip(path)
+	case strings.HasSuffix(lowerPath, ".tar.gz"), strings.HasSuffix(lowerPath, ".tgz"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		return ReadTar(path, gz)
+	case strings.HasSuffix(lowerPath, ".tar"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return ReadTar(path, f)
+	}
+	return nil, fmt.Errorf(ErrArchiveUnsupported, path)
+}
+
+func readZip(path string) (*Archive, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	archive := &Archive{Path: path, Files: make(map[string][]byte)}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		archive.Files[cleanEntryName(f.Name)] = data
+	}
+	return archive, nil
+}
+
+// ReadTar reads an uncompressed tar stream, name is only used for error messages
+func ReadTar(name string, r io.Reader) (*Archive, error) {
+	archive := &Archive{Path: name, Files: make(map[string][]byte)}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		archive.Files[cleanEntryName(hdr.Name)] = data
+	}
+	return archive, nil
+}
+
+func cleanEntryName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+}
+
+// Names returns the archive entries in lexical order, same as filepath.Walk would
+func (a *Archive) Names() []string {
+	names := make([]string, 0, len(a.Files))
+	for name := range a.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *Archive) Open(name string) (io.ReadCloser, error) {
+	data, ok := a.Files[name]
+	if !ok {
+		return nil, fmt.Errorf(ErrArchiveFileNotFound, name)
+	}
+	return namedReader{bytes.NewReader(data), filepath.Join(a.Path, name)}, nil
+}
+
+// GetSubDirectories pairs the .ann and .txt entries of the archive the same way GetSubDirectories does for a folder
+func (a *Archive) GetSubDirectories() ([]string, []string, error) {
+	annMult, textMult, unpaired := a.PairDocuments()
+	if len(unpaired) > 0 {
+		return []string{}, []string{}, unpaired[0]
+	}
+	return annMult, textMult, nil
+}
+
+// PairDocuments is the archive counterpart of PairDocuments
+func (a *Archive) PairDocuments() ([]string, []string, []*UnpairedFileError) {
+	annMult := []string{}
+	textMult := []string{}
+	unpaired := []*UnpairedFileError{}
+
+	for _, name := range a.Names() {
+		switch {
+		case strings.HasSuffix(name, dotAnnSuffix):
+			txtName := strings.TrimSuffix(name, dotAnnSuffix) + dotTxtSuffix
+			if _, ok := a.Files[txtName]; !ok {
+				unpaired = append(unpaired, &UnpairedFileError{name, txtName})
+				continue
+			}
+			annMult = append(annMult, name)
+			textMult = append(textMult, txtName)
+		case strings.HasSuffix(name, dotTxtSuffix):
+			annName := strings.TrimSuffix(name, dotTxtSuffix) + dotAnnSuffix
+			if _, ok := a.Files[annName]; !ok {
+				unpaired = append(unpaired, &UnpairedFileError{name, annName})
+			}
+		}
+	}
+	return annMult, textMult, unpaired
+}
+
+// RootConf returns the name of the `annotation.conf` entry closest to the root of the archive,
+// or an empty string if the archive has none
+func (a *Archive) RootConf() string {
+	confPath := ""
+	for _, name := range a.Names() {
+		if path.Base(name) != "annotation.conf" {
+			continue
+		}
+		if confPath == "" || strings.Count(name, "/") < strings.Count(confPath, "/") {
+			confPath = name
+		}
+	}
+	return confPath
+}
+
+// GetNearestConf is the archive counterpart of GetNearestConf
+func (a *Archive) GetNearestConf(docPath string) (string, error) {
+	if confPath := a.GetNearestFile(docPath, "annotation.conf"); confPath != "" {
+		return confPath, nil
+	}
+	return "", &NoConfError{filepath.Join(a.Path, docPath)}
+}
+
+// GetNearestFile is the archive counterpart of GetNearestFile
+func (a *Archive) GetNearestFile(docPath, name string) string {
+	dir := path.Dir(docPath)
+	for {
+		filePath := path.Join(dir, name)
+		if _, ok := a.Files[filePath]; ok {
+			return filePath
+		}
+		if dir == "." || dir == "/" {
+			break
+		}
+		dir = path.Dir(dir)
+	}
+	return ""
+}
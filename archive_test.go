@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ArchiveSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *ArchiveSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "archive")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *ArchiveSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+// collectionFiles returns the files of the folder keyed by their slash separated path relative to the folder
+func (suite *ArchiveSuite) collectionFiles(root string) map[string][]byte {
+	files := make(map[string][]byte)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		files[filepath.ToSlash(rel)] = data
+		return err
+	})
+	suite.Nil(err)
+	return files
+}
+
+func (suite *ArchiveSuite) writeZip(name string, files map[string][]byte) string {
+	path := filepath.Join(suite.TmpDir, name)
+	f, err := os.Create(path)
+	suite.Nil(err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		suite.Nil(err)
+		_, err = w.Write(data)
+		suite.Nil(err)
+	}
+	suite.Nil(zw.Close())
+	return path
+}
+
+func (suite *ArchiveSuite) writeTarGz(name string, files map[string][]byte) string {
+	path := filepath.Join(suite.TmpDir, name)
+	f, err := os.Create(path)
+	suite.Nil(err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		suite.Nil(tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err = tw.Write(data)
+		suite.Nil(err)
+	}
+	suite.Nil(tw.Close())
+	suite.Nil(gz.Close())
+	return path
+}
+
+func (suite *ArchiveSuite) convert(folderPath string) string {
+	output := filepath.Join(suite.TmpDir, filepath.Base(folderPath)+".jsonl")
+	err := handleMain(Options{FolderPath: folderPath, OutputFile: output})
+	suite.Nil(err)
+
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	return string(data)
+}
+
+func (suite *ArchiveSuite) TestArchiveMatchesFolder() {
+	files := suite.collectionFiles("./testData/news")
+	expected := suite.convert("./testData/news")
+
+	suite.Equal(expected, suite.convert(suite.writeZip("news.zip", files)))
+	suite.Equal(expected, suite.convert(suite.writeTarGz("news.tar.gz", files)))
+}
+
+func (suite *ArchiveSuite) TestArchiveInvalid() {
+	files := suite.collectionFiles("./testData/invalid-files/no-corresponding-txt")
+	err := handleMain(Options{FolderPath: suite.writeZip("no-txt.zip", files)})
+	suite.NotNil(err)
+
+	files = suite.collectionFiles("./testData/invalid-files/no-entities")
+	err = handleMain(Options{FolderPath: suite.writeTarGz("no-entities.tgz", files)})
+	suite.NotNil(err)
+}
+
+func (suite *ArchiveSuite) TestArchiveNearestConf() {
+	archive := &Archive{Path: "nested.zip", Files: map[string][]byte{
+		"annotation.conf":            {},
+		"a/doc.ann":                  {},
+		"a/b/annotation.conf":        {},
+		"a/b/c/doc.ann":              {},
+		"other/annotation.conf.bak":  {},
+		"other/doc.ann":              {},
+		"other/nested/annotation.co": {},
+	}}
+	suite.Equal("annotation.conf", archive.RootConf())
+
+	for docPath, expected := range map[string]string{
+		"a/doc.ann":     "annotation.conf",
+		"a/b/c/doc.ann": "a/b/annotation.conf",
+		"other/doc.ann": "annotation.conf",
+	} {
+		confPath, err := archive.GetNearestConf(docPath)
+		suite.Nil(err)
+		suite.Equal(expected, confPath)
+	}
+
+	delete(archive.Files, "annotation.conf")
+	_, err := archive.GetNearestConf("a/doc.ann")
+	suite.NotNil(err)
+	suite.Equal("a/b/annotation.conf", archive.RootConf())
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+const (
+	cacheVersionFile = "VERSION"
+	cacheEntrySuffix = ".json"
+
+	WarnCacheDisabled = "conversion cache disabled: %v"
+	WarnCacheWrite    = "could not write the conversion cache: %v"
+)
+
+// cacheEntryName matches the name of a cache entry, a hex encoded sha256 key, OpenCache only removes those files
+var cacheEntryName = regexp.MustCompile(`^[0-9a-f]{64}` + regexp.QuoteMeta(cacheEntrySuffix) + `$`)
+
+var (
+	developmentBuildOnce sync.Once
+	developmentBuild     string
+)
+
+// buildID identifies the converter writing the cache, the hash of a development build is only computed once. Released builds are identified by Version, a development
+// build by the hash of its executable and of its dependencies, so rebuilding after a code change invalidates the
+// cache. It is "" when the executable cannot be read, the cache is then disabled.
+func buildID() string {
+	if Version != "development" {
+		return Version
+	}
+	developmentBuildOnce.Do(func() {
+		executable, err := os.Executable()
+		if err != nil {
+			return
+		}
+		data, err := ioutil.ReadFile(executable)
+		if err != nil {
+			return
+		}
+		h := sha256.New()
+		h.Write(data)
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, dep := range info.Deps {
+				h.Write([]byte(dep.Path + "@" + dep.Version + " " + dep.Sum + "\n"))
+			}
+		}
+		developmentBuild = Version + "-" + hex.EncodeToString(h.Sum(nil))
+	})
+	return developmentBuild
+}
+
+// ConvertedDocument holds what the conversion of an annotation file produced, it is what the cache stores
+type ConvertedDocument struct {
+	// Types are the entities counted without filtering by annotation.conf, one name per entity
+	Types     []string          `json:"types,omitempty"`
+	Conflicts []OverlapConflict `json:"conflicts,omitempty"`
+	Segmented []SegmentedEntity `json:"segmented,omitempty"`
+	Documents []*Document       `json:"documents"`
+	Records   []string          `json:"records"`
+
+	// failed holds the errors of the records of annPath that could not be converted, a document with failures
+	// is not cached
+	annPath string
+	failed  []error
+}
+
+// ConversionCache stores the converted documents keyed by the hash of their .ann, .txt and annotation.conf, so
+// only the documents that changed since the previous run are converted again
+type ConversionCache struct {
+	Dir string
+	// build is the buildID of the converter, computed once when the cache is opened
+	build string
+	// options is the fingerprint of the options changing the conversion, they are part of every key
+	options []byte
+}
+
+// OpenCache opens the cache in dir, the entries written by another build of the converter are removed. Only the
+// files named like a cache entry are removed, dir may hold other files.
+func OpenCache(dir string, options []byte) (*ConversionCache, error) {
+	build := buildID()
+	if build == "" {
+		return nil, errors.New("cannot identify the build of the converter")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	versionFile := filepath.Join(dir, cacheVersionFile)
+	if version, err := ioutil.ReadFile(versionFile); err != nil || string(version) != build {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !cacheEntryName.MatchString(entry.Name()) {
+				continue
+			}
+			if err = os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+		if err = writeFileAtomic(versionFile, []byte(build), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return &ConversionCache{Dir: dir, build: build, options: options}, nil
+}
+
+// Key hashes the converter build, the options, the paths and the content of the files of a document
+func (c *ConversionCache) Key(paths []string, contents ...[]byte) string {
+	h := sha256.New()
+	parts := append([][]byte{[]byte(c.build), c.options}, contents...)
+	for _, p := range paths {
+		parts = append(parts, []byte(p))
+	}
+	for _, part := range parts {
+		// Every part is prefixed by its length so two different lists of parts never hash the same bytes
+		binary.Write(h, binary.LittleEndian, int64(len(part)))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *ConversionCache) path(key string) string {
+	return filepath.Join(c.Dir, key+cacheEntrySuffix)
+}
+
+// Get returns the converted document stored under key, nil when there is none or it cannot be read
+func (c *ConversionCache) Get(key string) *ConvertedDocument {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	converted := &ConvertedDocument{}
+	if err = json.Unmarshal(data, converted); err != nil || len(converted.Documents) != len(converted.Records) {
+		return nil
+	}
+	return converted
+}
+
+func (c *ConversionCache) Put(key string, converted *ConvertedDocument) error {
+	data, err := json.Marshal(converted)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path(key), data, 0600)
+}
+
+// conversionOptions is the fingerprint of the options changing the records of a document, with the content
+// of the label map
+func conversionOptions(opts Options, tokenizer, splitter string) ([]byte, error) {
+	labelMap := []byte{}
+	if opts.LabelMap != "" {
+		var err error
+		if labelMap, err = ioutil.ReadFile(opts.LabelMap); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(struct {
+		AllTypes                   bool
+		Overlap, Segment, Crossing string
+		Window, Stride             int
+		WindowUnit                 string
+		OffsetUnit                 string
+		Strict                     bool
+		Tokenizer, Splitter        string
+		LabelMapExt                string
+		LabelMap                   []byte
+	}{
+		opts.AllTypes, opts.Overlap, opts.Segment, opts.Crossing, opts.Window, opts.Stride, opts.WindowUnit,
+		opts.OffsetUnit, opts.Strict, tokenizer, splitter, strings.ToLower(filepath.Ext(opts.LabelMap)), labelMap,
+	})
+}
+
+// cacheKey returns the key of a document, "" when one of its files cannot be read, the conversion then reports it
+func cacheKey(cache *ConversionCache, collection *Collection, annPath, txtPath, confPath, toolsPath string) string {
+	paths := []string{annPath, txtPath, confPath, toolsPath}
+	contents := [][]byte{}
+	for _, name := range paths {
+		if name == "" {
+			contents = append(contents, nil)
+			continue
+		}
+		data, err := collection.ReadFile(name)
+		if err != nil {
+			return ""
+		}
+		contents = append(contents, data)
+	}
+	if confPath != "" {
+		paths = append(paths, collection.ConfLabel(confPath))
+	}
+	return cache.Key(paths, contents...)
+}
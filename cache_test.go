@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheSuite struct {
+	suite.Suite
+	TmpDir     string
+	Collection string
+	CacheDir   string
+}
+
+func (suite *CacheSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "cache")
+	suite.Nil(err)
+	suite.TmpDir = dir
+	suite.Collection = filepath.Join(dir, "collection")
+	suite.CacheDir = filepath.Join(dir, "cache")
+	suite.Nil(os.Mkdir(suite.Collection, 0700))
+	suite.writeFile("annotation.conf", "[entities]\nPerson\nGPE\n[relations]\n[events]\n[attributes]\n")
+	suite.writeFile("a.txt", "John went to Paris.")
+	suite.writeFile("a.ann", "T1\tPerson 0 4\tJohn\nT2\tGPE 13 18\tParis\n")
+	suite.writeFile("b.txt", "Mary stayed.")
+	suite.writeFile("b.ann", "T1\tPerson 0 4\tMary\n")
+}
+
+func (suite *CacheSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *CacheSuite) writeFile(name, content string) {
+	suite.Nil(ioutil.WriteFile(filepath.Join(suite.Collection, name), []byte(content), 0600))
+}
+
+func (suite *CacheSuite) entries() []string {
+	entries, err := filepath.Glob(filepath.Join(suite.CacheDir, "*"+cacheEntrySuffix))
+	suite.Nil(err)
+	return entries
+}
+
+// convert runs a conversion with the cache and returns the Acharya records
+func (suite *CacheSuite) convert(opts Options) string {
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	opts.FolderPath = suite.Collection
+	opts.OutputFile = output
+	opts.OverWrite = true
+	suite.Nil(handleMain(opts))
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	return string(data)
+}
+
+func (suite *CacheSuite) TestCachedConversion() {
+	uncached := suite.convert(Options{})
+	suite.Empty(suite.entries())
+
+	suite.Equal(uncached, suite.convert(Options{CacheDir: suite.CacheDir}))
+	suite.Len(suite.entries(), 2)
+
+	// The records of the unchanged documents are read from the cache
+	for _, entry := range suite.entries() {
+		data, err := ioutil.ReadFile(entry)
+		suite.Nil(err)
+		suite.Nil(ioutil.WriteFile(entry, []byte(strings.Replace(string(data), "Mary", "Anne", -1)), 0600))
+	}
+	suite.Contains(suite.convert(Options{CacheDir: suite.CacheDir}), "Anne stayed.")
+
+	// Only the edited document is converted again
+	suite.writeFile("a.ann", "T1\tPerson 0 4\tJohn\n")
+	converted := suite.convert(Options{CacheDir: suite.CacheDir})
+	suite.NotContains(converted, "GPE")
+	suite.Contains(converted, "Anne stayed.")
+	suite.Len(suite.entries(), 3)
+
+	// The options changing the records are part of the key
+	suite.Contains(suite.convert(Options{CacheDir: suite.CacheDir, Segment: SegmentSentence}), "Mary stayed.")
+	suite.Len(suite.entries(), 5)
+
+	// The conf is part of the key
+	suite.writeFile("annotation.conf", "[entities]\nGPE\n[relations]\n[events]\n[attributes]\n")
+	suite.NotContains(suite.convert(Options{CacheDir: suite.CacheDir}), "Person")
+}
+
+func (suite *CacheSuite) TestVersionInvalidatesCache() {
+	suite.convert(Options{CacheDir: suite.CacheDir})
+	suite.Len(suite.entries(), 2)
+
+	version := Version
+	defer func() { Version = version }()
+	Version = "v-next"
+	_, err := OpenCache(suite.CacheDir, nil)
+	suite.Nil(err)
+	suite.Empty(suite.entries())
+	data, err := ioutil.ReadFile(filepath.Join(suite.CacheDir, cacheVersionFile))
+	suite.Nil(err)
+	suite.Equal("v-next", string(data))
+}
+
+func (suite *CacheSuite) TestOnlyCacheEntriesAreRemoved() {
+	suite.Nil(os.MkdirAll(suite.CacheDir, 0700))
+	unrelated := filepath.Join(suite.CacheDir, "package.json")
+	suite.Nil(ioutil.WriteFile(unrelated, []byte("{}"), 0600))
+	suite.convert(Options{CacheDir: suite.CacheDir})
+
+	version := Version
+	defer func() { Version = version }()
+	Version = "v-next"
+	_, err := OpenCache(suite.CacheDir, nil)
+	suite.Nil(err)
+	suite.FileExists(unrelated)
+	suite.Equal([]string{unrelated}, suite.entries())
+}
+
+func (suite *CacheSuite) TestDevelopmentBuildID() {
+	version := Version
+	defer func() { Version = version }()
+	Version = "development"
+	suite.NotEqual("development", buildID())
+	suite.True(strings.HasPrefix(buildID(), "development-"))
+}
+
+func (suite *CacheSuite) TestFailedRecordsAreNotCached() {
+	suite.writeFile("b.ann", "T1\tPerson 0 40\tMary\n")
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	opts := Options{FolderPath: suite.Collection, OutputFile: output, KeepGoing: true, CacheDir: suite.CacheDir}
+	err := handleMain(opts)
+	var failed *DocumentsFailedError
+	suite.True(errors.As(err, &failed))
+	suite.Len(suite.entries(), 1)
+
+	// The failure is reported again by the next run
+	opts.OverWrite = true
+	suite.True(errors.As(handleMain(opts), &failed))
+}
+
+func (suite *CacheSuite) TestCacheKey() {
+	cache, err := OpenCache(suite.CacheDir, []byte("options"))
+	suite.Nil(err)
+	suite.NotEqual(cache.Key([]string{"ab", "c"}), cache.Key([]string{"a", "bc"}))
+	suite.Equal(cache.Key([]string{"a"}, []byte("x")), cache.Key([]string{"a"}, []byte("x")))
+
+	other, err := OpenCache(suite.CacheDir, []byte("other options"))
+	suite.Nil(err)
+	suite.NotEqual(cache.Key([]string{"a"}), other.Key([]string{"a"}))
+}
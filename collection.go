@@ -0,0 +1,234 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Collection is the list of documents a run works on, wherever they are read from
+type Collection struct {
+	Ann  []string
+	Txt  []string
+	Open func(name string) (io.ReadCloser, error)
+	// ConfFor returns the `annotation.conf` a document is validated and filtered against
+	ConfFor func(annPath string) (string, error)
+	// RootConf is checked even when no document ends up using it
+	RootConf string
+	// NearestFile returns the file named name closest to the document at docPath, `tools.conf` or `visual.conf`,
+	// "" when there is none. An empty docPath looks in the root of the collection only.
+	NearestFile func(docPath, name string) string
+	// ConfLabel is how the conf of a document is recorded in the output metadata
+	ConfLabel func(confPath string) string
+	// Unpaired are the .ann and .txt files of the folder without their pair, only listed with `--keep-going`
+	Unpaired []*UnpairedFileError
+
+	confEntities    map[string]map[string]bool
+	confHierarchies map[string]map[string]string
+	confOrders      map[string][]string
+	confTools       map[string]*ToolsConf
+}
+
+// OpenCollection lists the documents selected by the input options: a folder, an archive,
+// stdin or the `--ann`, `--files-from` and `--include` flags
+func OpenCollection(opts Options) (*Collection, error) {
+	c := &Collection{
+		Open:            openFile,
+		ConfLabel:       filepath.ToSlash,
+		confEntities:    make(map[string]map[string]bool),
+		confHierarchies: make(map[string]map[string]string),
+		confOrders:      make(map[string][]string),
+		confTools:       make(map[string]*ToolsConf),
+	}
+	var err error
+
+	var archive *Archive
+	switch {
+	case opts.FolderPath == StdinPath:
+		archive, err = ReadStdinArchive()
+		if err != nil {
+			return nil, err
+		}
+		// The conf is optional on stdin, `--conf` is used when it is missing
+		if archive.RootConf() == "" && opts.ConfFile != "" {
+			confData, err := ioutil.ReadFile(opts.ConfFile)
+			if err != nil {
+				return nil, err
+			}
+			archive.Files["annotation.conf"] = confData
+		}
+	case IsArchive(opts.FolderPath):
+		// Archives are read into memory instead of being extracted to disk
+		archive, err = OpenArchive(opts.FolderPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case archive != nil:
+		c.Ann, c.Txt, c.Unpaired = archive.PairDocuments()
+		c.Ann, c.Txt = FilterDocuments(c.Ann, c.Txt, opts.Include, opts.Exclude, "")
+		c.Unpaired = filterUnpaired(c.Unpaired, opts.Include, opts.Exclude, "")
+		c.Open = archive.Open
+		c.ConfFor = archive.GetNearestConf
+		c.RootConf = archive.RootConf()
+		c.NearestFile = func(docPath, name string) string {
+			if docPath == "" {
+				docPath = name
+			}
+			return archive.GetNearestFile(docPath, name)
+		}
+	case opts.FolderPath != "":
+		c.Ann, c.Txt, c.Unpaired, err = PairDocuments(opts.FolderPath)
+		if err != nil {
+			return nil, err
+		}
+		c.Ann, c.Txt = FilterDocuments(c.Ann, c.Txt, opts.Include, opts.Exclude, opts.FolderPath)
+		c.Unpaired = filterUnpaired(c.Unpaired, opts.Include, opts.Exclude, opts.FolderPath)
+		c.ConfFor = func(annPath string) (string, error) {
+			return GetNearestConf(opts.FolderPath, annPath)
+		}
+		c.NearestFile = func(docPath, name string) string {
+			if docPath == "" {
+				docPath = filepath.Join(opts.FolderPath, name)
+			}
+			return GetNearestFile(opts.FolderPath, docPath, name)
+		}
+		if _, err := os.Stat(filepath.Join(opts.FolderPath, "annotation.conf")); err == nil {
+			c.RootConf = filepath.Join(opts.FolderPath, "annotation.conf")
+		}
+		c.ConfLabel = func(confPath string) string {
+			if rel, err := filepath.Rel(opts.FolderPath, confPath); err == nil {
+				return filepath.ToSlash(rel)
+			}
+			return filepath.ToSlash(confPath)
+		}
+	default:
+		c.Ann, c.Txt, err = ResolveDocuments(opts)
+		if err != nil {
+			return nil, err
+		}
+		c.ConfFor = func(string) (string, error) {
+			return opts.ConfFile, nil
+		}
+		c.RootConf = opts.ConfFile
+		// The files are next to `--conf`, there is no directory to look in when it is read from stdin
+		c.NearestFile = func(_, name string) string {
+			if opts.ConfFile == "" || opts.ConfFile == StdinPath {
+				return ""
+			}
+			filePath := filepath.Join(filepath.Dir(opts.ConfFile), name)
+			if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+				return ""
+			}
+			return filePath
+		}
+	}
+
+	// A file without its pair fails the run, with `--keep-going` it is reported as a failed document
+	if len(c.Unpaired) > 0 && !opts.KeepGoing {
+		return nil, c.Unpaired[0]
+	}
+	return c, nil
+}
+
+// filterUnpaired applies `--include` and `--exclude` to the unpaired files, as FilterDocuments does to the .ann
+// file of their document
+func filterUnpaired(unpaired []*UnpairedFileError, include, exclude []string, root string) []*UnpairedFileError {
+	filtered := []*UnpairedFileError{}
+	for _, file := range unpaired {
+		annPath := file.Path
+		if strings.HasSuffix(file.Missing, dotAnnSuffix) {
+			annPath = file.Missing
+		}
+		if kept, _ := FilterDocuments([]string{annPath}, []string{file.Path}, include, exclude, root); len(kept) > 0 {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+// Entities returns the `[entities]` of the conf at confPath, every conf is only read once
+func (c *Collection) Entities(confPath string) (map[string]bool, error) {
+	if entities, ok := c.confEntities[confPath]; ok {
+		return entities, nil
+	}
+
+	confFile, cErr := c.Open(confPath)
+	if cErr != nil {
+		return nil, cErr
+	}
+	defer confFile.Close()
+
+	entities, err := ParseEntities(confFile)
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) && parseErr.Path == "" {
+			parseErr.Path = confPath
+		}
+		return nil, err
+	}
+	c.confEntities[confPath] = entities
+	return entities, nil
+}
+
+// DocumentTypes returns the conf used by the document at annPath and the entity types to convert. The types
+// are nil, every type is converted, with allTypes or when no `annotation.conf` applies to the document.
+func (c *Collection) DocumentTypes(annPath string, allTypes bool) (string, map[string]bool, error) {
+	confPath, err := c.ConfFor(annPath)
+	var noConf *NoConfError
+	switch {
+	case errors.As(err, &noConf):
+		return "", nil, nil
+	case err != nil:
+		return "", nil, err
+	case confPath == "" || allTypes:
+		return confPath, nil, nil
+	}
+	entities, err := c.Entities(confPath)
+	return confPath, entities, err
+}
+
+// Hierarchy returns the parent of every type of the `[entities]` of the conf at confPath
+func (c *Collection) Hierarchy(confPath string) (map[string]string, error) {
+	if hierarchy, ok := c.confHierarchies[confPath]; ok {
+		return hierarchy, nil
+	}
+
+	confFile, err := c.Open(confPath)
+	if err != nil {
+		return nil, err
+	}
+	defer confFile.Close()
+
+	c.confHierarchies[confPath] = GetEntityHierarchyFromFile(confFile)
+	return c.confHierarchies[confPath], nil
+}
+
+func (c *Collection) ReadFile(name string) ([]byte, error) {
+	f, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// DocumentNames returns the path of every .ann file relative to root, the collection was opened from,
+// so the same document can be found in several collections
+func (c *Collection) DocumentNames(root string) []string {
+	names := []string{}
+	for _, annPath := range c.Ann {
+		name := annPath
+		// Archive entries are already relative to the archive
+		if rel, err := filepath.Rel(root, annPath); err == nil && !IsArchive(root) && root != StdinPath {
+			name = rel
+		}
+		names = append(names, filepath.ToSlash(name))
+	}
+	return names
+}
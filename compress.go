@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+
+	ErrUnsupportedCompression = "unsupported compression: %s, expected `gzip` or `zstd`"
+)
+
+// compressionExts select the compression of an output file from its extension
+var compressionExts = map[string]string{".gz": CompressGzip, ".zst": CompressZstd}
+
+func ValidateCompression(compression string) error {
+	if compression != CompressGzip && compression != CompressZstd {
+		return fmt.Errorf(ErrUnsupportedCompression, compression)
+	}
+	return nil
+}
+
+// CompressionOf returns the compression selected by the extension of the file, "" when it is not compressed
+func CompressionOf(name string) string {
+	return compressionExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// CompressionExt returns the compression extension of the file, `.gz` for `out.jsonl.gz`, or ""
+func CompressionExt(name string) string {
+	if CompressionOf(name) == "" {
+		return ""
+	}
+	return filepath.Ext(name)
+}
+
+// nopWriteCloser leaves the uncompressed output as it is
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// compressWriter compresses what is written to w, Close flushes the compressed stream without closing w
+func compressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "":
+		return nopWriteCloser{w}, nil
+	case CompressGzip:
+		return gzip.NewWriter(w), nil
+	case CompressZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf(ErrUnsupportedCompression, compression)
+}
+
+// writeCompressed streams what write writes through the compression to w, the output is buffered and never
+// held in memory as a whole
+func writeCompressed(w io.Writer, compression string, write func(io.Writer) error) error {
+	buffered := bufio.NewWriter(w)
+	cw, err := compressWriter(buffered, compression)
+	if err != nil {
+		return err
+	}
+	if err = write(cw); err != nil {
+		cw.Close()
+		return err
+	}
+	if err = cw.Close(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// writeOutputStream prints what write writes, compressed by `--compress`, or saves it to outputFile, compressed
+// when its extension is `.gz` or `.zst`
+func writeOutputStream(outputFile string, mode OutputMode, write func(io.Writer) error) error {
+	stream, err := openOutputStream(outputFile, mode)
+	if err != nil {
+		return err
+	}
+	if err = write(stream); err != nil {
+		stream.Abort()
+		return err
+	}
+	return stream.Commit()
+}
+
+// outputStream is an output written while the documents are converted, compressed the way writeOutputStream
+// compresses it. A file is written to a temporary file renamed into place by Commit, stdout is printed as it comes.
+type outputStream struct {
+	io.Writer
+	compressor io.WriteCloser
+	buffered   *bufio.Writer
+	// file is nil for stdout
+	file *atomicFile
+}
+
+// openOutputStream opens outputFile, or stdout when it is empty or `-`. A file that already exists is only
+// overwritten with mode.OverWrite.
+func openOutputStream(outputFile string, mode OutputMode) (*outputStream, error) {
+	stream := &outputStream{}
+	var w io.Writer = os.Stdout
+	compression := mode.Compression
+	if outputFile != "" && outputFile != StdinPath {
+		if !mode.OverWrite {
+			if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
+				return nil, fmt.Errorf("%s: %s", outputFile, ErrFlagFileAlreadyExists)
+			}
+		}
+		file, err := createAtomicFile(outputFile, mode.Perm)
+		if err != nil {
+			return nil, err
+		}
+		stream.file = file
+		w = file
+		compression = CompressionOf(outputFile)
+	}
+
+	stream.buffered = bufio.NewWriter(w)
+	compressor, err := compressWriter(stream.buffered, compression)
+	if err != nil {
+		stream.Abort()
+		return nil, err
+	}
+	stream.compressor = compressor
+	stream.Writer = compressor
+	return stream, nil
+}
+
+// finish ends the compressed stream and flushes it, a file is then ready to be renamed into place
+func (s *outputStream) finish() error {
+	err := s.compressor.Close()
+	if err == nil {
+		err = s.buffered.Flush()
+	}
+	if s.file == nil {
+		return err
+	}
+	if err != nil {
+		s.file.Abort()
+		return err
+	}
+	return s.file.finish()
+}
+
+// Commit completes the output, a file is renamed into place
+func (s *outputStream) Commit() error {
+	if err := s.finish(); err != nil {
+		return err
+	}
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.rename(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, InfoSuccessfullyGenFile+"\n", s.file.name)
+	return nil
+}
+
+// Abort removes the temporary file of the output, what was printed to stdout stays printed
+func (s *outputStream) Abort() {
+	if s.file != nil {
+		s.file.Abort()
+	}
+}
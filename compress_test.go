@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *CompressSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "compress")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *CompressSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+// decompress reads the whole content of a gzip or zstd stream
+func (suite *CompressSuite) decompress(data []byte, compression string) string {
+	var r io.Reader
+	switch compression {
+	case CompressGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		suite.Nil(err)
+		r = gz
+	case CompressZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		suite.Nil(err)
+		defer zr.Close()
+		r = zr
+	}
+	decompressed, err := ioutil.ReadAll(r)
+	suite.Nil(err)
+	return string(decompressed)
+}
+
+func (suite *CompressSuite) TestCompressionOf() {
+	suite.Equal(CompressGzip, CompressionOf("out.jsonl.gz"))
+	suite.Equal(CompressZstd, CompressionOf(filepath.Join("dir", "out.conll.ZST")))
+	suite.Equal("", CompressionOf("out.jsonl"))
+	suite.Equal(".gz", CompressionExt("out.jsonl.gz"))
+	suite.Equal("", CompressionExt("out.jsonl"))
+
+	suite.Nil(ValidateCompression(CompressZstd))
+	suite.EqualError(ValidateCompression("bzip2"), "unsupported compression: bzip2, expected `gzip` or `zstd`")
+	suite.EqualError(ValidateFlags(Options{FolderPath: "./testData/news", Compress: "xz"}), "unsupported compression: xz, expected `gzip` or `zstd`")
+}
+
+func (suite *CompressSuite) TestWriteCompressed() {
+	for _, compression := range []string{"", CompressGzip, CompressZstd} {
+		b := &bytes.Buffer{}
+		suite.Nil(writeCompressed(b, compression, func(w io.Writer) error {
+			for i := 0; i < 1000; i++ {
+				if _, err := io.WriteString(w, "{\"Data\":\"Sony rocks\"}\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		expected := string(bytes.Repeat([]byte("{\"Data\":\"Sony rocks\"}\n"), 1000))
+		if compression == "" {
+			suite.Equal(expected, b.String())
+			continue
+		}
+		suite.True(b.Len() < len(expected), compression)
+		suite.Equal(expected, suite.decompress(b.Bytes(), compression), compression)
+	}
+}
+
+func (suite *CompressSuite) TestCompressedOutputs() {
+	expected := filepath.Join(suite.TmpDir, "expected.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: "./testData/news", OutputFile: expected}))
+	original, err := ioutil.ReadFile(expected)
+	suite.Nil(err)
+
+	gz := filepath.Join(suite.TmpDir, "out.jsonl.gz")
+	zst := filepath.Join(suite.TmpDir, "out.jsonl.zst")
+	opts := Options{FolderPath: "./testData/news", Outputs: []string{gz, "acharya=" + zst}}
+	suite.Nil(ValidateFlags(opts))
+	suite.Nil(handleMain(opts))
+	for output, compression := range map[string]string{gz: CompressGzip, zst: CompressZstd} {
+		data, err := ioutil.ReadFile(output)
+		suite.Nil(err)
+		suite.Equal(string(original), suite.decompress(data, compression), output)
+	}
+
+	// The splits keep the compression extension
+	opts = Options{FolderPath: "./testData/news", Split: "train=0.5,test=0.5", Outputs: []string{filepath.Join(suite.TmpDir, "split.jsonl.gz")}}
+	suite.Nil(handleMain(opts))
+	for _, name := range []string{"split.train.jsonl.gz", "split.test.jsonl.gz"} {
+		data, err := ioutil.ReadFile(filepath.Join(suite.TmpDir, name))
+		suite.Nil(err, name)
+		suite.NotEmpty(suite.decompress(data, CompressGzip), name)
+	}
+}
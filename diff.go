@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	ErrDiffArgs          = "the diff command compares two collections or two JSONL files, received %d"
+	ErrDiffUnknownFormat = "unknown diff format: %s, expected `text` or `json`"
+
+	ChangeAdded     = "added"
+	ChangeRemoved   = "removed"
+	ChangeRetyped   = "retyped"
+	ChangeRespanned = "respanned"
+)
+
+type DiffEntity struct {
+	Begin int    `json:"begin"`
+	End   int    `json:"end"`
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+}
+
+// EntityChange is an entity added, removed, retyped or re-spanned between the old and the new version
+type EntityChange struct {
+	Document string      `json:"document"`
+	Kind     string      `json:"kind"`
+	Old      *DiffEntity `json:"old,omitempty"`
+	New      *DiffEntity `json:"new,omitempty"`
+}
+
+// DiffReport is the output of the `diff` command
+type DiffReport struct {
+	Old       string         `json:"old"`
+	New       string         `json:"new"`
+	Documents int            `json:"documents"`
+	OnlyInOld []string       `json:"only_in_old"`
+	OnlyInNew []string       `json:"only_in_new"`
+	Added     int            `json:"added"`
+	Removed   int            `json:"removed"`
+	Retyped   int            `json:"retyped"`
+	Respanned int            `json:"respanned"`
+	Changes   []EntityChange `json:"changes"`
+}
+
+// diffDocument is a document of one side of the diff
+type diffDocument struct {
+	Name     string
+	Text     string
+	Entities []AcharyaEntity
+}
+
+// IsAcharyaFile reports whether path is a converted JSONL file rather than a collection
+func IsAcharyaFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".jsonl" || ext == ".json"
+}
+
+// readDiffDocuments reads the documents of a collection, or the records of an Acharya JSONL file
+func readDiffDocuments(path string, include, exclude []string) ([]diffDocument, error) {
+	docs := []diffDocument{}
+
+	if IsAcharyaFile(path) {
+		f, err := openFile(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		records, err := ReadAcharya(f)
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			docs = append(docs, diffDocument{fmt.Sprintf("%s:%d", path, i+1), record.Data, record.Entities})
+		}
+		return docs, nil
+	}
+
+	collection, err := OpenCollection(Options{FolderPath: path, Include: include, Exclude: exclude})
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range collection.DocumentNames(path) {
+		text, err := collection.ReadFile(collection.Txt[i])
+		if err != nil {
+			return nil, err
+		}
+		textBounds, err := collection.ReadEntities(collection.Ann[i])
+		if err != nil {
+			return nil, err
+		}
+		entities := []AcharyaEntity{}
+		for _, tb := range textBounds {
+			entities = append(entities, AcharyaEntity{tb.Begin(), tb.End(), tb.Type})
+		}
+		docs = append(docs, diffDocument{name, string(text), entities})
+	}
+	return docs, nil
+}
+
+// ComputeDiff pairs the documents of both versions, by their path when both are collections and by their
+// text otherwise, and lists how their entities changed
+func ComputeDiff(oldPath, newPath string, oldDocs, newDocs []diffDocument) *DiffReport {
+	report := &DiffReport{Old: oldPath, New: newPath, OnlyInOld: []string{}, OnlyInNew: []string{}, Changes: []EntityChange{}}
+
+	byName := !IsAcharyaFile(oldPath) && !IsAcharyaFile(newPath)
+	key := func(doc diffDocument) string {
+		if byName {
+			return doc.Name
+		}
+		return doc.Text
+	}
+
+	// Several records can hold the same text, they are paired in order
+	oldByKey := make(map[string][]int)
+	for i, doc := range oldDocs {
+		oldByKey[key(doc)] = append(oldByKey[key(doc)], i)
+	}
+	paired := make([]bool, len(oldDocs))
+
+	for _, newDoc := range newDocs {
+		candidates := oldByKey[key(newDoc)]
+		if len(candidates) == 0 {
+			report.OnlyInNew = append(report.OnlyInNew, newDoc.Name)
+			continue
+		}
+		oldByKey[key(newDoc)] = candidates[1:]
+		paired[candidates[0]] = true
+		report.Documents++
+
+		for _, change := range DiffEntities(newDoc.Name, oldDocs[candidates[0]].Text, newDoc.Text, oldDocs[candidates[0]].Entities, newDoc.Entities) {
+			switch change.Kind {
+			case ChangeAdded:
+				report.Added++
+			case ChangeRemoved:
+				report.Removed++
+			case ChangeRetyped:
+				report.Retyped++
+			case ChangeRespanned:
+				report.Respanned++
+			}
+			report.Changes = append(report.Changes, change)
+		}
+	}
+	for i, doc := range oldDocs {
+		if !paired[i] {
+			report.OnlyInOld = append(report.OnlyInOld, doc.Name)
+		}
+	}
+	return report
+}
+
+// DiffEntities matches the entities by span: entities with the same span and another type are retyped,
+// overlapping entities of the same type are re-spanned, the others are added or removed. The text of the old
+// entities is read from oldText, that of the new ones from newText.
+func DiffEntities(name, oldText, newText string, oldEntities, newEntities []AcharyaEntity) []EntityChange {
+	changes := []EntityChange{}
+	oldMatched := make([]bool, len(oldEntities))
+	newMatched := make([]bool, len(newEntities))
+
+	diffEntity := func(text string, ent AcharyaEntity) *DiffEntity {
+		entText, _ := GetSubString(text, ent.Begin, ent.End)
+		return &DiffEntity{ent.Begin, ent.End, ent.Name, entText}
+	}
+
+	// Every pass only looks at the entities left unmatched by the previous ones
+	passes := []struct {
+		kind  string
+		match func(o, n AcharyaEntity) bool
+	}{
+		{"", func(o, n AcharyaEntity) bool { return o == n }},
+		{ChangeRetyped, func(o, n AcharyaEntity) bool { return o.Begin == n.Begin && o.End == n.End }},
+		{ChangeRespanned, func(o, n AcharyaEntity) bool { return o.Name == n.Name && o.Begin < n.End && n.Begin < o.End }},
+	}
+	for _, pass := range passes {
+		for j, n := range newEntities {
+			if newMatched[j] {
+				continue
+			}
+			for i, o := range oldEntities {
+				if oldMatched[i] || !pass.match(o, n) {
+					continue
+				}
+				oldMatched[i], newMatched[j] = true, true
+				if pass.kind != "" {
+					changes = append(changes, EntityChange{name, pass.kind, diffEntity(oldText, o), diffEntity(newText, n)})
+				}
+				break
+			}
+		}
+	}
+
+	for i, o := range oldEntities {
+		if !oldMatched[i] {
+			changes = append(changes, EntityChange{Document: name, Kind: ChangeRemoved, Old: diffEntity(oldText, o)})
+		}
+	}
+	for j, n := range newEntities {
+		if !newMatched[j] {
+			changes = append(changes, EntityChange{Document: name, Kind: ChangeAdded, New: diffEntity(newText, n)})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].begin() < changes[j].begin()
+	})
+	return changes
+}
+
+func (c EntityChange) begin() int {
+	if c.New != nil {
+		return c.New.Begin
+	}
+	return c.Old.Begin
+}
+
+// WriteText prints the changes of every document as a human readable report
+func (r *DiffReport) WriteText(w io.Writer) error {
+	document := ""
+	for _, change := range r.Changes {
+		if change.Document != document {
+			document = change.Document
+			fmt.Fprintf(w, "%s\n", document)
+		}
+		switch change.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(w, "  + %s %d %d %q\n", change.New.Type, change.New.Begin, change.New.End, change.New.Text)
+		case ChangeRemoved:
+			fmt.Fprintf(w, "  - %s %d %d %q\n", change.Old.Type, change.Old.Begin, change.Old.End, change.Old.Text)
+		case ChangeRetyped:
+			fmt.Fprintf(w, "  ~ %s -> %s %d %d %q\n", change.Old.Type, change.New.Type, change.New.Begin, change.New.End, change.New.Text)
+		case ChangeRespanned:
+			fmt.Fprintf(w, "  ~ %s %d %d %q -> %d %d %q\n", change.New.Type, change.Old.Begin, change.Old.End, change.Old.Text, change.New.Begin, change.New.End, change.New.Text)
+		}
+	}
+
+	for _, name := range r.OnlyInOld {
+		fmt.Fprintf(w, "only in %s: %s\n", r.Old, name)
+	}
+	for _, name := range r.OnlyInNew {
+		fmt.Fprintf(w, "only in %s: %s\n", r.New, name)
+	}
+	_, err := fmt.Fprintf(w, "%d documents compared: %d added, %d removed, %d retyped, %d re-spanned\n",
+		r.Documents, r.Added, r.Removed, r.Retyped, r.Respanned)
+	return err
+}
+
+// runDiff implements the `diff` command
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	include := flags.StringArrayP("include", "i", []string{}, "Glob pattern of the annotation files (.ann) to compare, relative to both collections. Can be repeated")
+	exclude := flags.StringArrayP("exclude", "x", []string{}, "Glob pattern of the annotation files (.ann) to skip. Can be repeated")
+	format := flags.String("format", "text", "Output format: text or json")
+	oFileName := flags.StringP("output", "o", "", "Name of the output file to be generated")
+	overWrite := flags.BoolP("force", "f", false, "If you wish to overwrite the generated file then set force to true")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf(ErrDiffArgs, flags.NArg())
+	}
+
+	oldPath, newPath := flags.Arg(0), flags.Arg(1)
+	oldDocs, err := readDiffDocuments(oldPath, *include, *exclude)
+	if err != nil {
+		return err
+	}
+	newDocs, err := readDiffDocuments(newPath, *include, *exclude)
+	if err != nil {
+		return err
+	}
+	report := ComputeDiff(oldPath, newPath, oldDocs, newDocs)
+
+	buf := &bytes.Buffer{}
+	switch *format {
+	case "text":
+		err = report.WriteText(buf)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(report, "", "  ")
+		buf.Write(append(data, '\n'))
+	default:
+		err = fmt.Errorf(ErrDiffUnknownFormat, *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*oFileName, buf.String(), defaultOutputMode(*overWrite))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffSuite struct {
+	suite.Suite
+}
+
+func (suite *DiffSuite) TestReadAcharya() {
+	records, err := ReadAcharya(strings.NewReader("{\"Data\":\"Sony\",\"Entities\":[[0,4,\"Organization\"]],\"Meta\":{\"conf\":\"annotation.conf\"}}\n"))
+	suite.Nil(err)
+	suite.Equal([]AcharyaRecord{{"Sony", []AcharyaEntity{{0, 4, "Organization"}}, &RecordMeta{Conf: "annotation.conf"}}}, records)
+
+	_, err = ReadAcharya(strings.NewReader("{\"Data\":\"a\",\"Entities\":[[0,1,\"X\"]]}\n\n{\"Data\":\"b\",\"Entities\":[[0,\"X\"]]}\n"))
+	suite.EqualError(err, "3: file follows unknown format: expected an entity as [begin, end, \"Name\"] received [0,\"X\"]")
+	var parseErr *ParseError
+	suite.True(errors.As(err, &parseErr))
+	suite.True(errors.Is(err, ErrParseBadFormat))
+	suite.Equal(3, parseErr.Line)
+}
+
+func (suite *DiffSuite) TestDiffEntities() {
+	text := "Sony bought Columbia Pictures in Tokyo"
+	oldEntities := []AcharyaEntity{{0, 4, "Organization"}, {12, 20, "Organization"}, {33, 38, "Organization"}, {5, 11, "Event"}}
+	newEntities := []AcharyaEntity{{0, 4, "Organization"}, {12, 29, "Organization"}, {33, 38, "GPE"}, {21, 29, "Thing"}}
+
+	changes := DiffEntities("doc", text, text, oldEntities, newEntities)
+	suite.Equal([]EntityChange{
+		{"doc", ChangeRemoved, &DiffEntity{5, 11, "Event", "bought"}, nil},
+		{"doc", ChangeRespanned, &DiffEntity{12, 20, "Organization", "Columbia"}, &DiffEntity{12, 29, "Organization", "Columbia Pictures"}},
+		{"doc", ChangeAdded, nil, &DiffEntity{21, 29, "Thing", "Pictures"}},
+		{"doc", ChangeRetyped, &DiffEntity{33, 38, "Organization", "Tokyo"}, &DiffEntity{33, 38, "GPE", "Tokyo"}},
+	}, changes)
+}
+
+func (suite *DiffSuite) TestDiffEntitiesEditedText() {
+	// Paired by path, the text of the document changed between the versions
+	changes := DiffEntities("doc", "Sony in Tokyo", "Sony Corp in Tokyo", []AcharyaEntity{{8, 13, "GPE"}}, []AcharyaEntity{{13, 18, "GPE"}})
+	suite.Equal([]EntityChange{
+		{"doc", ChangeRemoved, &DiffEntity{8, 13, "GPE", "Tokyo"}, nil},
+		{"doc", ChangeAdded, nil, &DiffEntity{13, 18, "GPE", "Tokyo"}},
+	}, changes)
+}
+
+func (suite *DiffSuite) TestComputeDiff() {
+	oldDocs := []diffDocument{{"a.jsonl:1", "Sony", []AcharyaEntity{{0, 4, "Organization"}}}, {"a.jsonl:2", "gone", nil}}
+	newDocs := []diffDocument{{"b.jsonl:1", "new", nil}, {"b.jsonl:2", "Sony", []AcharyaEntity{{0, 4, "GPE"}}}}
+
+	report := ComputeDiff("a.jsonl", "b.jsonl", oldDocs, newDocs)
+	suite.Equal(1, report.Documents)
+	suite.Equal(1, report.Retyped)
+	suite.Equal([]string{"a.jsonl:2"}, report.OnlyInOld)
+	suite.Equal([]string{"b.jsonl:1"}, report.OnlyInNew)
+
+	buf := &bytes.Buffer{}
+	suite.Nil(report.WriteText(buf))
+	suite.Equal("b.jsonl:2\n  ~ Organization -> GPE 0 4 \"Sony\"\nonly in a.jsonl: a.jsonl:2\nonly in b.jsonl: b.jsonl:1\n"+
+		"1 documents compared: 0 added, 0 removed, 1 retyped, 0 re-spanned\n", buf.String())
+}
+
+func (suite *DiffSuite) TestDiffCollections() {
+	dir, err := ioutil.TempDir("", "diff")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "news.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: "./testData/news", OutputFile: output}))
+
+	oldDocs, err := readDiffDocuments("./testData/news", nil, nil)
+	suite.Nil(err)
+	newDocs, err := readDiffDocuments(output, nil, nil)
+	suite.Nil(err)
+
+	report := ComputeDiff("./testData/news", output, oldDocs, newDocs)
+	suite.Equal(13, report.Documents)
+	suite.Empty(report.Changes)
+	suite.Empty(report.OnlyInOld)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrOffsetNotANumber = "annotation offset is not a number"
+	ErrSpanOutOfRange   = "annotation span does not fit the txt data"
+	ErrConfBadSection   = "conf section is not closed by `]`"
+	ErrTextMismatch     = "annotation text does not match the txt data"
+)
+
+// Sentinel categories of parse errors, use errors.Is to check which one a *ParseError belongs to
+var (
+	ErrParseDiscontinuous   = errors.New(ErrDiscontinuosTextboundAnnNotSupported)
+	ErrParseBadFormat       = errors.New(strings.TrimSuffix(ErrBadFormat, ": "))
+	ErrParseBadFormatTab    = errors.New(ErrBadFormatTab)
+	ErrParseBadOffset       = errors.New(ErrOffsetNotANumber)
+	ErrParseBadAnnotationID = errors.New(ErrTxtAnnBadFormat)
+	ErrParseBadSpan         = errors.New(ErrSpanOutOfRange)
+	ErrParseNoEntities      = errors.New(ErrNoEntities)
+	ErrParseBadSection      = errors.New(ErrConfBadSection)
+	ErrParseTextMismatch    = errors.New(ErrTextMismatch)
+)
+
+// ParseError describes a problem found while parsing a brat standoff file.
+// Line and Column are 1 based, 0 means unknown.
+type ParseError struct {
+	Path   string
+	Line   int
+	Column int
+	Raw    string
+	ID     string
+	Kind   error
+	Err    error
+}
+
+// Error formats the error in a compiler like `file:line:column: message` style
+func (e *ParseError) Error() string {
+	location := []string{}
+	if e.Path != "" {
+		location = append(location, e.Path)
+	}
+	if e.Line > 0 {
+		location = append(location, strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			location = append(location, strconv.Itoa(e.Column))
+		}
+	}
+
+	msg := e.Message()
+	if e.ID != "" {
+		msg = e.ID + ": " + msg
+	}
+
+	if len(location) == 0 {
+		return msg
+	}
+	return strings.Join(location, ":") + ": " + msg
+}
+
+// Message returns the error message without the location and annotation ID
+func (e *ParseError) Message() string {
+	if e.Err != nil {
+		return e.Kind.Error() + ": " + e.Err.Error()
+	}
+	return e.Kind.Error()
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// NoConfError is returned when no `annotation.conf` applies to a document
+type NoConfError struct {
+	Path string
+}
+
+func (e *NoConfError) Error() string {
+	return fmt.Sprintf(ErrNoConfFound, e.Path)
+}
+
+// UnpairedFileError is returned for a .ann file without its .txt, or a .txt file without its .ann
+type UnpairedFileError struct {
+	Path    string
+	Missing string
+}
+
+func (e *UnpairedFileError) Error() string {
+	return fmt.Sprintf(ErrFilesNotExist, e.Missing)
+}
+
+// annotationID returns the leading token of an annotation line, the ID even when the tab after it is missing
+func annotationID(line string) string {
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// badFormatDetail explains what was expected in the middle field of a text-bound annotation
+func badFormatDetail(field string) error {
+	return fmt.Errorf("expected \"<type> <start> <end>\" received %q", field)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ParseErrorTest struct {
+	AnnFilePath string
+	Kind        error
+	Line        int
+	Column      int
+	Message     string
+}
+
+type ParseErrorSuite struct {
+	suite.Suite
+	TestData []ParseErrorTest
+}
+
+func (suite *ParseErrorSuite) SetupTest() {
+	suite.TestData = []ParseErrorTest{
+		{
+			"./testData/invalid-files/dicontinous-text-bound-annotations/030-login.ann",
+			ErrParseDiscontinuous, 1, 4,
+			"./testData/invalid-files/dicontinous-text-bound-annotations/030-login.ann:1:4: T1: " + ErrDiscontinuosTextboundAnnNotSupported,
+		},
+		{
+			"./testData/invalid-files/invalid-ann-atoi/pos1.ann",
+			ErrParseBadOffset, 1, 20, "",
+		},
+		{
+			"./testData/invalid-files/invalid-ann-tab/030-login.ann",
+			ErrParseBadFormatTab, 1, 1,
+			"./testData/invalid-files/invalid-ann-tab/030-login.ann:1:1: T1: " + ErrBadFormatTab,
+		},
+		{
+			"./testData/invalid-files/invalid-ann-space/030-login.ann",
+			ErrParseBadFormat, 1, 4, "",
+		},
+	}
+}
+
+func (suite *ParseErrorSuite) TestGenNumberEntityArrErrors() {
+	entitiesMap := map[string]bool{"Organization": true}
+
+	for _, v := range suite.TestData {
+		annFile, aErr := os.Open(v.AnnFilePath)
+		suite.Nil(aErr)
+		defer annFile.Close()
+
+		_, err := GenNumberEntityArr(entitiesMap, annFile)
+		suite.True(errors.Is(err, v.Kind), v.AnnFilePath)
+
+		var parseErr *ParseError
+		suite.True(errors.As(err, &parseErr))
+		suite.Equal(v.AnnFilePath, parseErr.Path)
+		suite.Equal(v.Line, parseErr.Line)
+		suite.Equal(v.Column, parseErr.Column, v.AnnFilePath)
+		suite.NotEmpty(parseErr.Raw)
+		if v.Message != "" {
+			suite.Equal(v.Message, err.Error())
+		}
+	}
+}
+
+func (suite *ParseErrorSuite) TestGetTextAnnNumError() {
+	_, err := GetTextAnnNum("TINVALID\tOrganization 0 4\tSony")
+	suite.True(errors.Is(err, ErrParseBadAnnotationID))
+
+	var numErr *strconv.NumError
+	suite.True(errors.As(err, &numErr))
+}
+
+func (suite *ParseErrorSuite) TestAnnotationIDWithoutTab() {
+	_, err := GenNumberEntityArr(nil, strings.NewReader("T1 Organization 0 4\tSony\n"))
+	var parseErr *ParseError
+	suite.True(errors.As(err, &parseErr))
+	suite.True(errors.Is(err, ErrParseBadFormatTab))
+	suite.Equal("T1", parseErr.ID)
+
+	_, err = ParseStandoff(strings.NewReader("T1\tOrganization 0 4\tSony\nR1 Family Arg1:T1\n"))
+	suite.True(errors.As(err, &parseErr))
+	suite.Equal("R1", parseErr.ID)
+	suite.Equal(2, parseErr.Line)
+}
+
+func (suite *ParseErrorSuite) TestConfErrors() {
+	_, err := ParseEntities(strings.NewReader("[relations]\n\n[entities]\n# nothing yet\n\n[events]\n"))
+	var parseErr *ParseError
+	suite.True(errors.As(err, &parseErr))
+	suite.True(errors.Is(err, ErrParseNoEntities))
+	suite.Equal(3, parseErr.Line)
+
+	_, err = ReadConfSections(strings.NewReader("[labels]\nPerson | Person | Per\n[drawing\n"))
+	suite.True(errors.As(err, &parseErr))
+	suite.True(errors.Is(err, ErrParseBadSection))
+	suite.Equal(3, parseErr.Line)
+	suite.Equal("3:1: "+ErrConfBadSection, err.Error())
+}
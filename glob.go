@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	dotAnnSuffix = ".ann"
+	dotTxtSuffix = ".txt"
+)
+
+// MatchGlob reports whether the slash separated name matches pattern.
+// On top of the path.Match syntax `**` matches any number of directories, including none.
+func MatchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(path.Clean(pattern), "/"), strings.Split(path.Clean(name), "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// globBase returns the leading directories of pattern that do not contain any wildcard
+func globBase(pattern string) string {
+	base := []string{}
+	for _, segment := range strings.Split(path.Clean(pattern), "/") {
+		if strings.ContainsAny(segment, "*?[\\") {
+			break
+		}
+		base = append(base, segment)
+	}
+	if len(base) == 0 {
+		return "."
+	}
+	if base[0] == "" {
+		return "/" + path.Join(base[1:]...)
+	}
+	return path.Join(base...)
+}
+
+// ExpandGlob returns the .ann files matching pattern, in the order filepath.Walk finds them
+func ExpandGlob(pattern string) ([]string, error) {
+	pattern = filepath.ToSlash(pattern)
+	matches := []string{}
+
+	err := filepath.Walk(filepath.FromSlash(globBase(pattern)),
+		func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !info.IsDir() && strings.HasSuffix(p, dotAnnSuffix) && MatchGlob(pattern, filepath.ToSlash(p)) {
+				matches = append(matches, p)
+			}
+			return nil
+		})
+	return matches, err
+}
+
+// ReadFilesFrom reads a list of .ann files, one per line. Empty lines and lines starting with `#` are skipped.
+func ReadFilesFrom(listPath string) ([]string, error) {
+	listFile, err := openFile(listPath)
+	if err != nil {
+		return nil, err
+	}
+	defer listFile.Close()
+
+	files := []string{}
+	scanner := bufio.NewScanner(listFile)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		files = append(files, line)
+	}
+	return files, scanner.Err()
+}
+
+// TxtForAnn returns the .txt file that goes with the .ann file at annPath
+func TxtForAnn(annPath string) string {
+	return strings.TrimSuffix(annPath, dotAnnSuffix) + dotTxtSuffix
+}
+
+// ResolveDocuments builds the list of .ann and .txt files to convert from `--ann`, `--txt`,
+// `--files-from` and `--include`. When `--txt` is not given the .txt files are discovered from
+// the base name of every .ann file.
+func ResolveDocuments(opts Options) ([]string, []string, error) {
+	annMult := []string{}
+	textMult := []string{}
+	seen := make(map[string]bool)
+
+	add := func(annPath, txtPath string) {
+		if seen[filepath.Clean(annPath)] {
+			return
+		}
+		seen[filepath.Clean(annPath)] = true
+		annMult = append(annMult, annPath)
+		textMult = append(textMult, txtPath)
+	}
+
+	if !IsEmptyString(opts.AnnFiles) {
+		annFiles := strings.Split(opts.AnnFiles, ",")
+		txtFiles := strings.Split(opts.TxtFiles, ",")
+		if !IsEmptyString(opts.TxtFiles) && len(annFiles) != len(txtFiles) {
+			return nil, nil, fmt.Errorf(ErrNoAnnNoTxtNotMatch, annFiles, len(annFiles), txtFiles, len(txtFiles))
+		}
+		for i, annPath := range annFiles {
+			annPath = strings.TrimSpace(annPath)
+			if IsEmptyString(opts.TxtFiles) {
+				add(annPath, TxtForAnn(annPath))
+			} else {
+				add(annPath, strings.TrimSpace(txtFiles[i]))
+			}
+		}
+	}
+
+	if opts.FilesFrom != "" {
+		files, err := ReadFilesFrom(opts.FilesFrom)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, annPath := range files {
+			add(annPath, TxtForAnn(annPath))
+		}
+	}
+
+	for _, pattern := range opts.Include {
+		matches, err := ExpandGlob(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, annPath := range matches {
+			add(annPath, TxtForAnn(annPath))
+		}
+	}
+
+	annMult, textMult = FilterDocuments(annMult, textMult, nil, opts.Exclude, "")
+	return annMult, textMult, nil
+}
+
+// FilterDocuments keeps the documents whose .ann path, relative to root, matches one of the include
+// patterns (all of them if there are none) and none of the exclude patterns
+func FilterDocuments(annMult, textMult, include, exclude []string, root string) ([]string, []string) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return annMult, textMult
+	}
+
+	matchAny := func(patterns []string, name string) bool {
+		for _, pattern := range patterns {
+			if MatchGlob(filepath.ToSlash(pattern), name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	filteredAnn := []string{}
+	filteredTxt := []string{}
+	for i, annPath := range annMult {
+		name := annPath
+		if root != "" {
+			if rel, err := filepath.Rel(root, annPath); err == nil {
+				name = rel
+			}
+		}
+		name = filepath.ToSlash(name)
+
+		if len(include) > 0 && !matchAny(include, name) {
+			continue
+		}
+		if matchAny(exclude, name) {
+			continue
+		}
+		filteredAnn = append(filteredAnn, annPath)
+		filteredTxt = append(filteredTxt, textMult[i])
+	}
+	return filteredAnn, filteredTxt
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MatchGlobTest struct {
+	Pattern  string
+	Name     string
+	Expected bool
+}
+
+type GlobSuite struct {
+	suite.Suite
+	TestData []MatchGlobTest
+}
+
+func (suite *GlobSuite) SetupTest() {
+	suite.TestData = []MatchGlobTest{
+		{"corpus/**/*.ann", "corpus/a.ann", true},
+		{"corpus/**/*.ann", "corpus/a/b/c.ann", true},
+		{"corpus/**/*.ann", "corpus/a/b/c.txt", false},
+		{"corpus/**/*.ann", "other/a.ann", false},
+		{"./corpus/*.ann", "corpus/a.ann", true},
+		{"corpus/*.ann", "corpus/a/b.ann", false},
+		{"**", "a/b/c.ann", true},
+		{"ned/**", "ned/ned.train-doc-118.ann", true},
+		{"**/esp.train-doc-1??.ann", "esp/esp.train-doc-100.ann", true},
+		{"**/esp.train-doc-1??.ann", "esp/esp.train-doc-1400.ann", false},
+		{"corpus/[ab].ann", "corpus/c.ann", false},
+	}
+}
+
+func (suite *GlobSuite) TestMatchGlob() {
+	for _, v := range suite.TestData {
+		suite.Equal(v.Expected, MatchGlob(v.Pattern, v.Name), v)
+	}
+}
+
+func (suite *GlobSuite) TestExpandGlob() {
+	matches, err := ExpandGlob("./testData/CoNLL-ST_2002/**/*.ann")
+	suite.Nil(err)
+	suite.Equal(19, len(matches))
+	suite.Equal("testData/CoNLL-ST_2002/esp/esp.train-doc-100.ann", filepath.ToSlash(matches[0]))
+
+	matches, err = ExpandGlob("./testData/does-not-exist/*.ann")
+	suite.Nil(err)
+	suite.Equal(0, len(matches))
+}
+
+func (suite *GlobSuite) TestResolveDocuments() {
+	dir, err := ioutil.TempDir("", "files-from")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	list := filepath.Join(dir, "list.txt")
+	suite.Nil(ioutil.WriteFile(list, []byte("# news\n./testData/news/000-introduction.ann\n\ntestData/news/010-navigation.ann\n"), 0600))
+
+	anns, txts, err := ResolveDocuments(Options{
+		AnnFiles:  "./testData/news/000-introduction.ann",
+		FilesFrom: list,
+		Include:   []string{"testData/CoNLL-ST_2002/**/*.ann"},
+		Exclude:   []string{"**/esp/**"},
+	})
+	suite.Nil(err)
+	suite.Equal(12, len(anns))
+	suite.Equal("./testData/news/000-introduction.ann", anns[0])
+	suite.Equal("./testData/news/000-introduction.txt", txts[0])
+	suite.Equal("testData/news/010-navigation.txt", txts[1])
+	for i, ann := range anns {
+		suite.NotContains(ann, "/esp/")
+		suite.Equal(TxtForAnn(ann), txts[i])
+	}
+
+	_, _, err = ResolveDocuments(Options{AnnFiles: "a.ann,b.ann", TxtFiles: "a.txt"})
+	suite.NotNil(err)
+}
+
+func (suite *GlobSuite) TestHandleMainInclude() {
+	dir, err := ioutil.TempDir("", "include")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "out.jsonl")
+	suite.Nil(ValidateFlags(Options{Include: []string{"testData/CoNLL-ST_2002/ned/*.ann"}, ConfFile: "testData/CoNLL-ST_2002/annotation.conf"}))
+	suite.NotNil(ValidateFlags(Options{TxtFiles: "a.txt", Include: []string{"*.ann"}, ConfFile: "annotation.conf"}))
+
+	err = handleMain(Options{
+		Include:    []string{"testData/CoNLL-ST_2002/ned/*.ann"},
+		ConfFile:   "testData/CoNLL-ST_2002/annotation.conf",
+		OutputFile: output,
+	})
+	suite.Nil(err)
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Equal(10, strings.Count(string(data), "\n"))
+
+	err = handleMain(Options{
+		FolderPath: "./testData/CoNLL-ST_2002",
+		Exclude:    []string{"ned/**", "esp/esp.train-doc-1*.ann"},
+		OutputFile: output,
+		OverWrite:  true,
+	})
+	suite.Nil(err)
+	data, err = ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Equal(7, strings.Count(string(data), "\n"))
+}
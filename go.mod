@@ -3,8 +3,11 @@ module github.com/astutic/bratStandoffConverter
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.12.3
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.2.2
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	// equivRelation is how `*` annotations are declared in `[relations]`
+	equivRelation      = "<REL-TYPE>:symmetric-transitive"
+	HeaderInferredConf = "# Inferred by brat-standoff-to-json infer-conf from %d documents, review it before annotating\n"
+)
+
+// inferredArgs collects the roles of a relation or event type in the order they are first found,
+// with the types of their arguments and how many times they occur in one annotation
+type inferredArgs struct {
+	roles []string
+	types map[string][]string
+	min   map[string]int
+	max   map[string]int
+	count int
+}
+
+func newInferredArgs() *inferredArgs {
+	return &inferredArgs{types: make(map[string][]string), min: make(map[string]int), max: make(map[string]int)}
+}
+
+// add records the arguments of one annotation, typeOf resolves the type of the annotation an argument refers to
+func (a *inferredArgs) add(args []Argument, typeOf map[string]string) {
+	occurrences := make(map[string]int)
+	for _, arg := range args {
+		role := eventRole(arg.Role, args)
+		if _, ok := a.types[role]; !ok {
+			a.roles = append(a.roles, role)
+			a.types[role] = []string{}
+			// A role missing from the annotations seen before is optional
+			a.min[role] = 0
+			if a.count == 0 {
+				a.min[role] = -1
+			}
+		}
+		occurrences[role]++
+		if argType, ok := typeOf[arg.ID]; ok && !containsString(a.types[role], argType) {
+			a.types[role] = append(a.types[role], argType)
+		}
+	}
+	for _, role := range a.roles {
+		if a.min[role] == -1 || occurrences[role] < a.min[role] {
+			a.min[role] = occurrences[role]
+		}
+		a.max[role] = maxInt(a.max[role], occurrences[role])
+	}
+	a.count++
+}
+
+// eventRole removes the number brat appends to a repeated role, `Theme2` is a second `Theme`
+func eventRole(role string, args []Argument) string {
+	base := strings.TrimRight(role, "0123456789")
+	if n, err := strconv.Atoi(role[len(base):]); err != nil || n < 2 || base == "" {
+		return role
+	}
+	for _, arg := range args {
+		if arg.Role == base {
+			return base
+		}
+	}
+	return role
+}
+
+// multiplicity returns the brat suffix of a role: `?` optional, `*` any number, `+` at least one, `{n}` exactly n
+func (a *inferredArgs) multiplicity(role string) string {
+	min, max := a.min[role], a.max[role]
+	switch {
+	case min == 1 && max == 1:
+		return ""
+	case min == max:
+		return fmt.Sprintf("{%d}", min)
+	case min == 0 && max == 1:
+		return "?"
+	case min == 0:
+		return "*"
+	default:
+		return "+"
+	}
+}
+
+func (a *inferredArgs) format(multiplicity bool) string {
+	formatted := []string{}
+	for _, role := range a.roles {
+		types := append([]string{}, a.types[role]...)
+		sort.Strings(types)
+		name := role
+		if multiplicity {
+			name += a.multiplicity(role)
+		}
+		formatted = append(formatted, name+":"+strings.Join(types, "|"))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// InferredConf is the `annotation.conf` describing the annotations found in a collection
+type InferredConf struct {
+	Documents  int
+	Entities   map[string]bool
+	Relations  map[string]*inferredArgs
+	Equivs     map[string]*inferredArgs
+	Events     map[string]*inferredArgs
+	Attributes map[string]*inferredAttribute
+}
+
+type inferredAttribute struct {
+	targets []string
+	values  []string
+}
+
+func NewInferredConf() *InferredConf {
+	return &InferredConf{
+		Entities:   make(map[string]bool),
+		Relations:  make(map[string]*inferredArgs),
+		Equivs:     make(map[string]*inferredArgs),
+		Events:     make(map[string]*inferredArgs),
+		Attributes: make(map[string]*inferredAttribute),
+	}
+}
+
+// Add records the annotation types of a document
+func (c *InferredConf) Add(standoff *Standoff) {
+	c.Documents++
+	typeOf := make(map[string]string)
+	triggers := make(map[string]bool)
+	for _, tb := range standoff.TextBounds {
+		typeOf[tb.ID] = tb.Type
+	}
+	for _, e := range standoff.Events {
+		typeOf[e.ID] = e.Type
+		triggers[e.Trigger] = true
+	}
+
+	// The types of the event triggers are declared by `[events]`, they are only entities when used on their own too
+	for _, tb := range standoff.TextBounds {
+		if !triggers[tb.ID] {
+			c.Entities[tb.Type] = true
+		}
+	}
+	for _, r := range standoff.Relations {
+		if c.Relations[r.Type] == nil {
+			c.Relations[r.Type] = newInferredArgs()
+		}
+		c.Relations[r.Type].add(r.Args, typeOf)
+	}
+	for _, e := range standoff.Equivs {
+		if c.Equivs[e.Type] == nil {
+			c.Equivs[e.Type] = newInferredArgs()
+		}
+		args := []Argument{}
+		for i, id := range e.IDs {
+			// Equivalences are declared as binary relations, all their members are of the same kind
+			args = append(args, Argument{fmt.Sprintf("Arg%d", minInt(i+1, 2)), id})
+		}
+		c.Equivs[e.Type].add(args, typeOf)
+	}
+	for _, e := range standoff.Events {
+		if c.Events[e.Type] == nil {
+			c.Events[e.Type] = newInferredArgs()
+		}
+		c.Events[e.Type].add(e.Args, typeOf)
+	}
+	for _, a := range standoff.Attributes {
+		attribute := c.Attributes[a.Type]
+		if attribute == nil {
+			attribute = &inferredAttribute{targets: []string{}, values: []string{}}
+			c.Attributes[a.Type] = attribute
+		}
+		if target, ok := typeOf[a.Target]; ok && !containsString(attribute.targets, target) {
+			attribute.targets = append(attribute.targets, target)
+		}
+		if a.Value != "" && !containsString(attribute.values, a.Value) {
+			attribute.values = append(attribute.values, a.Value)
+		}
+	}
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func sortedArgNames(args map[string]*inferredArgs) []string {
+	names := make(map[string]bool)
+	for name := range args {
+		names[name] = true
+	}
+	return sortedNames(names)
+}
+
+// String formats the conf the way brat reads it, every section lists its types in alphabetical order
+func (c *InferredConf) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, HeaderInferredConf, c.Documents)
+
+	b.WriteString("\n[entities]\n\n")
+	for _, name := range sortedNames(c.Entities) {
+		b.WriteString(name + "\n")
+	}
+
+	b.WriteString("\n[relations]\n\n")
+	for _, name := range sortedArgNames(c.Relations) {
+		fmt.Fprintf(b, "%s\t%s\n", name, c.Relations[name].format(false))
+	}
+	for _, name := range sortedArgNames(c.Equivs) {
+		fmt.Fprintf(b, "%s\t%s, %s\n", name, c.Equivs[name].format(false), equivRelation)
+	}
+
+	b.WriteString("\n[events]\n\n")
+	for _, name := range sortedArgNames(c.Events) {
+		if args := c.Events[name].format(true); args != "" {
+			fmt.Fprintf(b, "%s\t%s\n", name, args)
+		} else {
+			b.WriteString(name + "\n")
+		}
+	}
+
+	b.WriteString("\n[attributes]\n\n")
+	names := make(map[string]bool)
+	for name := range c.Attributes {
+		names[name] = true
+	}
+	for _, name := range sortedNames(names) {
+		attribute := c.Attributes[name]
+		targets := append([]string{}, attribute.targets...)
+		sort.Strings(targets)
+		fmt.Fprintf(b, "%s\tArg:%s", name, strings.Join(targets, "|"))
+		if len(attribute.values) > 0 {
+			values := append([]string{}, attribute.values...)
+			sort.Strings(values)
+			fmt.Fprintf(b, ", Value:%s", strings.Join(values, "|"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// InferConf reads every .ann file of the collection and describes their annotations
+func InferConf(collection *Collection) (*InferredConf, error) {
+	conf := NewInferredConf()
+	for _, annPath := range collection.Ann {
+		standoff, err := collection.ReadStandoff(annPath)
+		if err != nil {
+			return nil, err
+		}
+		conf.Add(standoff)
+	}
+	return conf, nil
+}
+
+// runInferConf implements the `infer-conf` command
+func runInferConf(args []string) error {
+	flags := flag.NewFlagSet("infer-conf", flag.ContinueOnError)
+	folderPath := flags.StringP("folderPath", "p", "", "Path to the folder (or archive) containing the collection")
+	include := flags.StringArrayP("include", "i", []string{}, "Glob pattern of the annotation files (.ann) to scan. Can be repeated")
+	exclude := flags.StringArrayP("exclude", "x", []string{}, "Glob pattern of the annotation files (.ann) to skip. Can be repeated")
+	oFileName := flags.StringP("output", "o", "", "Name of the annotation.conf to be generated")
+	overWrite := flags.BoolP("force", "f", false, "If you wish to overwrite the generated file then set force to true")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := Options{FolderPath: *folderPath, Include: *include, Exclude: *exclude}
+	if opts.FolderPath == "" && len(opts.Include) == 0 {
+		return fmt.Errorf(ErrValidateNoInput)
+	}
+
+	collection, err := OpenCollection(opts)
+	if err != nil {
+		return err
+	}
+	conf, err := InferConf(collection)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*oFileName, conf.String(), defaultOutputMode(*overWrite))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type InferSuite struct {
+	suite.Suite
+}
+
+func (suite *InferSuite) TestInferredConf() {
+	ann := "T1\tPerson 0 4\tJohn\nT2\tPerson 9 13\tMary\nT3\tMarry 5 8\twed\nT4\tGPE 17 22\tParis\n" +
+		"E1\tMarry:T3 Person-Arg:T1 Person-Arg2:T2 Place-Arg:T4\nR1\tLocated Arg1:T1 Arg2:T4\t\n" +
+		"A1\tNegation E1\nA2\tMention T1 Name\nA3\tMention T4 Nominal\n*\tAlias T1 T2\n"
+	standoff, err := ParseStandoff(strings.NewReader(ann))
+	suite.Nil(err)
+	conf := NewInferredConf()
+	conf.Add(standoff)
+
+	standoff, err = ParseStandoff(strings.NewReader("T1\tMarry 0 3\twed\nT2\tPerson 4 8\tJohn\nE1\tMarry:T1 Person-Arg:T2\n"))
+	suite.Nil(err)
+	conf.Add(standoff)
+
+	suite.Equal("# Inferred by brat-standoff-to-json infer-conf from 2 documents, review it before annotating\n"+
+		"\n[entities]\n\nGPE\nPerson\n"+
+		"\n[relations]\n\nLocated\tArg1:Person, Arg2:GPE\nAlias\tArg1:Person, Arg2:Person, <REL-TYPE>:symmetric-transitive\n"+
+		"\n[events]\n\nMarry\tPerson-Arg+:Person, Place-Arg?:GPE\n"+
+		"\n[attributes]\n\nMention\tArg:GPE|Person, Value:Name|Nominal\nNegation\tArg:Marry\n", conf.String())
+}
+
+func (suite *InferSuite) TestEventRole() {
+	args := []Argument{{"Theme", "T1"}, {"Theme2", "T2"}, {"Arg2", "T3"}}
+	suite.Equal("Theme", eventRole("Theme2", args))
+	suite.Equal("Arg2", eventRole("Arg2", args))
+	suite.Equal("Theme", eventRole("Theme", args))
+}
+
+func (suite *InferSuite) TestInferConfLoadable() {
+	dir, err := ioutil.TempDir("", "infer-conf")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	// The collection is copied without its annotation.conf, the inferred one filters the types the same way
+	for _, name := range []string{"000-introduction.txt", "000-introduction.ann", "040-text_span_annotation.txt", "040-text_span_annotation.ann"} {
+		data, err := ioutil.ReadFile(filepath.Join("testData/news", name))
+		suite.Nil(err)
+		suite.Nil(ioutil.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+	suite.Nil(runInferConf([]string{"-p", dir, "-o", filepath.Join(dir, "annotation.conf")}))
+	output := filepath.Join(dir, "out.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: dir, OutputFile: output}))
+
+	expected, err := ioutil.TempDir("", "infer-conf-expected")
+	suite.Nil(err)
+	defer os.RemoveAll(expected)
+	suite.Nil(handleMain(Options{FolderPath: "testData/news", Include: []string{"000-*", "040-*"}, OutputFile: filepath.Join(expected, "out.jsonl")}))
+	converted, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	original, err := ioutil.ReadFile(filepath.Join(expected, "out.jsonl"))
+	suite.Nil(err)
+	suite.Equal(string(original), string(converted))
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const ErrLabelMapBadCollapse = "`collapse` in %s should be true, false or a list of entity types"
+
+// LabelMap renames the entity types of the converted documents. Types are first collapsed to their
+// parent in the `[entities]` hierarchy of the conf, then renamed, then dropped.
+type LabelMap struct {
+	// Rename maps a type to its new name, several types can be mapped to the same name
+	Rename map[string]string `json:"rename" yaml:"rename"`
+	// Drop lists the types to remove, either their original or their new name
+	Drop []string `json:"drop" yaml:"drop"`
+	// Collapse is true to map every type to the top of its hierarchy, or a list of the types to map
+	// their descendants to
+	Collapse interface{} `json:"collapse" yaml:"collapse"`
+
+	collapseAll bool
+	collapseTo  map[string]bool
+	drop        map[string]bool
+}
+
+// LoadLabelMap reads a label map from a JSON file, or a YAML file when its extension is .yaml or .yml
+func LoadLabelMap(path string) (*LabelMap, error) {
+	f, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	labelMap := &LabelMap{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, labelMap)
+	default:
+		err = json.Unmarshal(data, labelMap)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	labelMap.drop = make(map[string]bool)
+	for _, name := range labelMap.Drop {
+		labelMap.drop[name] = true
+	}
+	labelMap.collapseTo = make(map[string]bool)
+	switch collapse := labelMap.Collapse.(type) {
+	case nil:
+	case bool:
+		labelMap.collapseAll = collapse
+	case []interface{}:
+		for _, name := range collapse {
+			str, ok := name.(string)
+			if !ok {
+				return nil, fmt.Errorf(ErrLabelMapBadCollapse, path)
+			}
+			labelMap.collapseTo[str] = true
+		}
+	default:
+		return nil, fmt.Errorf(ErrLabelMapBadCollapse, path)
+	}
+	return labelMap, nil
+}
+
+// NeedsHierarchy reports whether the conf hierarchy is used to map the types
+func (l *LabelMap) NeedsHierarchy() bool {
+	return l.collapseAll || len(l.collapseTo) > 0
+}
+
+// MapType returns the new name of the type, and false when it is dropped.
+// hierarchy maps every type to its parent, as returned by GetEntityHierarchyFromFile.
+func (l *LabelMap) MapType(name string, hierarchy map[string]string) (string, bool) {
+	if l.drop[name] {
+		return "", false
+	}
+
+	mapped := name
+	for parent, ok := hierarchy[name]; ok; parent, ok = hierarchy[parent] {
+		if l.collapseAll || l.collapseTo[parent] {
+			mapped = parent
+		}
+	}
+	if renamed, ok := l.Rename[mapped]; ok {
+		mapped = renamed
+	}
+	if l.drop[mapped] {
+		return "", false
+	}
+	return mapped, true
+}
+
+// Apply maps the types of the entities and removes the dropped ones
+func (l *LabelMap) Apply(entities []NumberAcharyaEntity, hierarchy map[string]string) []NumberAcharyaEntity {
+	mapped := []NumberAcharyaEntity{}
+	for _, ent := range entities {
+		name, ok := l.MapType(ent.Entity.Name, hierarchy)
+		if !ok {
+			continue
+		}
+		ent.Entity.Name = name
+		mapped = append(mapped, ent)
+	}
+	return mapped
+}
+
+// ApplyOrder maps a list of types, in order, keeping the first place of the types several types are mapped to
+func (l *LabelMap) ApplyOrder(order []string, hierarchy map[string]string) []string {
+	mapped := []string{}
+	for _, name := range order {
+		if name, ok := l.MapType(name, hierarchy); ok && !containsString(mapped, name) {
+			mapped = append(mapped, name)
+		}
+	}
+	return mapped
+}
+
+// GetEntityHierarchyFromFile maps every type of the `[entities]` section to its parent. Children are indented
+// with tabs below their parent, the `!` of the types that can't be annotated is removed.
+func GetEntityHierarchyFromFile(confFile io.Reader) map[string]string {
+	scanner := bufio.NewScanner(confFile)
+	scanner.Split(bufio.ScanLines)
+	startScan := false
+	hierarchy := make(map[string]string)
+	// parents holds the last type seen at every depth
+	parents := []string{}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "[entities]") {
+			startScan = true
+			continue
+		}
+		if !startScan || len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+
+		depth := len(line) - len(strings.TrimLeft(line, "\t"))
+		name := strings.TrimPrefix(strings.TrimSpace(line), "!")
+		if depth > len(parents) {
+			depth = len(parents)
+		}
+		parents = append(parents[:depth], name)
+		if depth > 0 {
+			hierarchy[name] = parents[depth-1]
+		}
+	}
+	return hierarchy
+}
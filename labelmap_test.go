@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LabelMapSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *LabelMapSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "label-map")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *LabelMapSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *LabelMapSuite) write(name, content string) string {
+	path := filepath.Join(suite.TmpDir, name)
+	suite.Nil(ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func (suite *LabelMapSuite) TestGetEntityHierarchyFromFile() {
+	conf := "[entities]\n# comment\nPerson\n!Location\n\tGPE\n\t\tCity\n\tFacility\nVehicle\n\tCar\n[relations]\nFoo\n\tBar\n"
+	suite.Equal(map[string]string{"GPE": "Location", "City": "GPE", "Facility": "Location", "Car": "Vehicle"},
+		GetEntityHierarchyFromFile(strings.NewReader(conf)))
+}
+
+func (suite *LabelMapSuite) TestLoadLabelMap() {
+	jsonPath := suite.write("map.json", `{"rename": {"GPE": "Location", "LOC": "Location"}, "drop": ["Money"], "collapse": true}`)
+	labelMap, err := LoadLabelMap(jsonPath)
+	suite.Nil(err)
+	suite.Equal(map[string]string{"GPE": "Location", "LOC": "Location"}, labelMap.Rename)
+	suite.True(labelMap.NeedsHierarchy())
+
+	yamlPath := suite.write("map.yaml", "rename:\n  GPE: Location\ndrop:\n  - Money\ncollapse:\n  - Vehicle\n")
+	labelMap, err = LoadLabelMap(yamlPath)
+	suite.Nil(err)
+	suite.Equal([]string{"Money"}, labelMap.Drop)
+	suite.True(labelMap.collapseTo["Vehicle"])
+
+	badPath := suite.write("bad.json", `{"collapse": "yes"}`)
+	_, err = LoadLabelMap(badPath)
+	suite.EqualError(err, "`collapse` in "+badPath+" should be true, false or a list of entity types")
+
+	_, err = LoadLabelMap(suite.write("broken.json", `{"rename": [`))
+	suite.NotNil(err)
+}
+
+func (suite *LabelMapSuite) TestMapType() {
+	hierarchy := map[string]string{"GPE": "Location", "City": "GPE", "Car": "Vehicle"}
+
+	labelMap := &LabelMap{Rename: map[string]string{"GPE": "LOC", "Location": "LOC"}, drop: map[string]bool{"Money": true}}
+	name, ok := labelMap.MapType("GPE", hierarchy)
+	suite.Equal("LOC", name)
+	suite.True(ok)
+	_, ok = labelMap.MapType("Money", hierarchy)
+	suite.False(ok)
+
+	labelMap.collapseAll = true
+	name, _ = labelMap.MapType("City", hierarchy)
+	suite.Equal("LOC", name)
+	name, _ = labelMap.MapType("Car", hierarchy)
+	suite.Equal("Vehicle", name)
+
+	labelMap = &LabelMap{collapseTo: map[string]bool{"GPE": true}}
+	name, _ = labelMap.MapType("City", hierarchy)
+	suite.Equal("GPE", name)
+	name, _ = labelMap.MapType("Car", hierarchy)
+	suite.Equal("Car", name)
+}
+
+func (suite *LabelMapSuite) TestHandleMainLabelMap() {
+	labelMapPath := suite.write("map.json", `{"rename": {"GPE": "Location", "Organization": "ORG"}, "drop": ["Person"]}`)
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+
+	err := handleMain(Options{FolderPath: "./testData/news", Include: []string{"000-*"}, OutputFile: output, LabelMap: labelMapPath})
+	suite.Nil(err)
+
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Contains(string(data), `"Entities":[[418,426,"ORG"],[456,468,"Money"],[540,545,"ORG"],[549,560,"Location"]]`)
+}
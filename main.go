@@ -2,9 +2,11 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -23,7 +25,7 @@ func printVersion() {
 
 const (
 	ErrNoEntities                           = "the conf file does not have an `[entities]` field or `[entities]` field is empty"
-	ErrMultipleConfFilesFound               = "multiple `annotation.conf` files found"
+	ErrNoConfFound                          = "no `annotation.conf` found for %s"
 	ErrDiscontinuosTextboundAnnNotSupported = "discontinuous text-bound annotations is not currently supported"
 
 	ErrSubStrNegativeStartPos         = "start position should be a positive number, Received start position %d"
@@ -42,19 +44,71 @@ const (
 	InfoSuccessfullyGenFile = "successfully generated file: %s"
 
 	ErrValidateNoAnnFiles         = "no annotation files specified in the input"
-	ErrValidateNoTxtFiles         = "no txt files specified in the input"
-	ErrValidateNoConfFile         = "no conf file specified in the input"
+	ErrValidateTxtWithoutAnn      = "txt files can only be specified together with `--ann`"
 	ErrValidateEmptyFolder        = "received empty folder path"
 	ErrValidateOutputFileNotFound = "force flag is provided but output file is not specified"
 
 	ErrNoAnnNoTxtNotMatch        = "the number of annotation files should be equal to the number of txt files,\n Received Annotation Files: %s Length: %d,Txt Files: %s Length: %d"
 	ErrAnnFileNotCorrespondToTxt = "expected annotation file: %s to correspond to: %s.txt Received: %s"
+
+	ErrDocumentsFailed        = "%d of %d documents failed to convert"
+	InfoSuccessfullyGenReport = "successfully generated error report: %s"
+	ErrValidateFileMode       = "invalid file mode: %s, expected octal permissions such as `0644`"
+	defaultFileMode           = 0600
+	InfoAllTypes              = "converted every entity type, without filtering by annotation.conf: %s"
 )
 
 func exit1() {
 	os.Exit(1)
 }
 
+// exit2 is used when the run completed with `--keep-going` but some documents failed
+func exit2() {
+	os.Exit(2)
+}
+
+// Options holds everything handleMain needs to know about a conversion run
+type Options struct {
+	FolderPath string
+	AnnFiles   string
+	TxtFiles   string
+	ConfFile   string
+	OutputFile string
+	// Outputs are the `--output` values, with their format, they replace OutputFile when given
+	Outputs     []string
+	OverWrite   bool
+	KeepGoing   bool
+	ErrorReport string
+	Include     []string
+	Exclude     []string
+	FilesFrom   string
+	Split       string
+	Seed        int64
+	Stratify    bool
+	GroupByDir  bool
+	LabelMap    string
+	Overlap     string
+	Segment     string
+	Crossing    string
+	Window      int
+	Stride      int
+	WindowUnit  string
+	// OffsetUnit counts the offsets of the Acharya records in chars, bytes or utf16, chars when empty
+	OffsetUnit string
+	// Strict validates every annotation of the .ann files and the text of the text-bound annotations
+	Strict     bool
+	Schema     string
+	Tokenizer  string
+	Splitter   string
+	AllTypes   bool
+	FileMode   string
+	Compress   string
+	ShardSize  int
+	ShardBytes string
+	// CacheDir holds the conversion cache, every document is converted when it is empty
+	CacheDir string
+}
+
 type AcharyaEntity struct {
 	Begin int
 	End   int
@@ -95,16 +149,26 @@ func GetSubString(originalString string, startPos, endPos int) (string, error) {
 	return val, nil
 }
 
-func GetEntitiesFromFile(confFile *os.File) map[string]bool {
+func GetEntitiesFromFile(confFile io.Reader) map[string]bool {
+	entities, _ := ParseEntities(confFile)
+	return entities
+}
 
+// ParseEntities returns the `[entities]` of the conf, with a *ParseError when the section is missing or empty
+func ParseEntities(confFile io.Reader) (map[string]bool, error) {
 	scanner := bufio.NewScanner(confFile)
 	scanner.Split(bufio.ScanLines)
 	startScan := false
 	entities := make(map[string]bool)
+	lineNo := 0
+	// sectionLine is the line of the `[entities]` header, reported when the section is empty
+	sectionLine := 0
 
 	for scanner.Scan() {
+		lineNo++
 		if strings.Contains(scanner.Text(), "[entities]") {
 			startScan = true
+			sectionLine = lineNo
 			continue
 		}
 		if startScan {
@@ -119,16 +183,32 @@ func GetEntitiesFromFile(confFile *os.File) map[string]bool {
 			entities[strings.TrimSpace(scanner.Text())] = true
 		}
 	}
-	return entities
+	if err := scanner.Err(); err != nil {
+		return entities, &ParseError{Path: readerName(confFile), Line: lineNo + 1, Kind: ErrParseBadFormat, Err: err}
+	}
+	if len(entities) == 0 {
+		return entities, &ParseError{Path: readerName(confFile), Line: sectionLine, Kind: ErrParseNoEntities}
+	}
+	return entities, nil
 }
 
 func GetSubDirectories(path string) ([]string, []string, error) {
-	const dotAnnSuffix = ".ann"
-	const dotTxtSuffix = ".txt"
+	annMult, textMult, unpaired, err := PairDocuments(path)
+	if err != nil {
+		return []string{}, []string{}, err
+	}
+	if len(unpaired) > 0 {
+		return []string{}, []string{}, unpaired[0]
+	}
+	return annMult, textMult, nil
+}
 
-	annConfCount := 0
+// PairDocuments lists the .ann and .txt files of the folder like GetSubDirectories, the files without their pair
+// are returned apart instead of failing the listing
+func PairDocuments(path string) ([]string, []string, []*UnpairedFileError, error) {
 	annMult := []string{}
 	textMult := []string{}
+	unpaired := []*UnpairedFileError{}
 
 	err := filepath.Walk(path,
 		func(path string, info os.FileInfo, err error) error {
@@ -139,29 +219,73 @@ func GetSubDirectories(path string) ([]string, []string, error) {
 			switch {
 			// .ann file should have a corresponding .txt file
 			case strings.HasSuffix(path, dotAnnSuffix):
-				if _, err := os.Stat(strings.TrimSuffix(path, dotAnnSuffix) + dotTxtSuffix); os.IsNotExist(err) {
-					return fmt.Errorf(ErrFilesNotExist, strings.TrimSuffix(path, dotAnnSuffix)+dotTxtSuffix)
+				txtPath := strings.TrimSuffix(path, dotAnnSuffix) + dotTxtSuffix
+				if _, err := os.Stat(txtPath); os.IsNotExist(err) {
+					unpaired = append(unpaired, &UnpairedFileError{path, txtPath})
+					return nil
 				}
 				annMult = append(annMult, path)
-				textMult = append(textMult, strings.TrimSuffix(path, dotAnnSuffix)+dotTxtSuffix)
-			// .ann file should have a corresponding .txt file
+				textMult = append(textMult, txtPath)
+			// .txt file should have a corresponding .ann file
 			case strings.HasSuffix(path, dotTxtSuffix):
-				if _, err := os.Stat(strings.TrimSuffix(path, dotTxtSuffix) + dotAnnSuffix); os.IsNotExist(err) {
-					return fmt.Errorf(ErrFilesNotExist, strings.TrimSuffix(path, dotTxtSuffix)+dotAnnSuffix)
+				annPath := strings.TrimSuffix(path, dotTxtSuffix) + dotAnnSuffix
+				if _, err := os.Stat(annPath); os.IsNotExist(err) {
+					unpaired = append(unpaired, &UnpairedFileError{path, annPath})
 				}
-			case strings.HasSuffix(path, "annotation.conf"):
-				annConfCount++
-			}
-
-			if annConfCount > 1 {
-				return errors.New(ErrMultipleConfFilesFound)
 			}
 			return nil
 		})
 	if err != nil {
-		return []string{}, []string{}, err
+		return nil, nil, nil, err
 	}
-	return annMult, textMult, nil
+	return annMult, textMult, unpaired, nil
+}
+
+// GetNearestConf resolves the configuration of a document the way brat does, by walking up from
+// the directory of the document to root and returning the first `annotation.conf` found
+func GetNearestConf(root, docPath string) (string, error) {
+	if confPath := GetNearestFile(root, docPath, "annotation.conf"); confPath != "" {
+		return confPath, nil
+	}
+	return "", &NoConfError{docPath}
+}
+
+// GetNearestFile walks up from the directory of the document to root like GetNearestConf, it returns the first
+// file named name found, or "" when there is none
+func GetNearestFile(root, docPath, name string) string {
+	root = filepath.Clean(root)
+	dir := filepath.Dir(docPath)
+	for {
+		filePath := filepath.Join(dir, name)
+		if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
+			return filePath
+		}
+		if rel, err := filepath.Rel(root, dir); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return ""
+}
+
+// readerName returns the file name behind r when it has one, used for error messages
+func readerName(r io.Reader) string {
+	if named, ok := r.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// openFile opens the file at name, or stdin when name is `-`
+func openFile(name string) (io.ReadCloser, error) {
+	if name == StdinPath {
+		data, err := readStdin()
+		if err != nil {
+			return nil, err
+		}
+		return namedReader{bytes.NewReader(data), stdinName}, nil
+	}
+	return os.Open(name)
 }
 
 func GetTextAnnNum(ann string) (int, error) {
@@ -169,52 +293,77 @@ func GetTextAnnNum(ann string) (int, error) {
 		annSplit := strings.Split(ann, "\t")
 		if len(annSplit[0]) > 1 {
 			noStr := annSplit[0][1:]
-			return strconv.Atoi(noStr)
+			no, err := strconv.Atoi(noStr)
+			if err != nil {
+				return no, &ParseError{Column: 2, Raw: ann, ID: annSplit[0], Kind: ErrParseBadAnnotationID, Err: err}
+			}
+			return no, nil
 		}
 	}
-	return 0, errors.New(ErrTxtAnnBadFormat)
+	return 0, &ParseError{Column: 1, Raw: ann, Kind: ErrParseBadAnnotationID}
 }
 
-func GenNumberEntityArr(entFromConf map[string]bool, aData *os.File) ([]NumberAcharyaEntity, error) {
+// GenNumberEntityArr reads the text-bound annotations whose type is in entFromConf, a nil entFromConf keeps every type
+func GenNumberEntityArr(entFromConf map[string]bool, aData io.Reader) ([]NumberAcharyaEntity, error) {
 	scanner := bufio.NewScanner(aData)
 	scanner.Split(bufio.ScanLines)
 
 	numberEntityArr := []NumberAcharyaEntity{}
+	lineNo := 0
 
 	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
 		// Uncomment the lines below to dispaly the ann file
 		// fmt.Println(strings.Repeat("#", 30), "Annotations", strings.Repeat("#", 30))
-		// fmt.Println(scanner.Text())
-		if strings.HasPrefix(scanner.Text(), "T") {
-			splitAnn := strings.Split(scanner.Text(), "\t")
+		// fmt.Println(line)
+		if strings.HasPrefix(line, "T") {
+			splitAnn := strings.Split(line, "\t")
+			parseErr := &ParseError{Path: readerName(aData), Line: lineNo, Column: 1, Raw: line, ID: annotationID(line)}
 			if len(splitAnn) == 3 {
+				// Columns are counted from the start of the field following the annotation ID
+				parseErr.Column = len(splitAnn[0]) + 2
 				if strings.Contains(splitAnn[1], ";") {
-					return []NumberAcharyaEntity{}, errors.New(ErrDiscontinuosTextboundAnnNotSupported)
+					parseErr.Kind = ErrParseDiscontinuous
+					return []NumberAcharyaEntity{}, parseErr
 				}
 				entAndPos := strings.Split(splitAnn[1], " ")
 				if (len(entAndPos)) == 3 {
-					if entFromConf[strings.TrimSpace(entAndPos[0])] {
+					if entFromConf == nil || entFromConf[strings.TrimSpace(entAndPos[0])] {
+						parseErr.Kind = ErrParseBadOffset
 						b, err := strconv.Atoi(entAndPos[1])
 						if err != nil {
-							return []NumberAcharyaEntity{}, err
+							parseErr.Column += len(entAndPos[0]) + 1
+							parseErr.Err = err
+							return []NumberAcharyaEntity{}, parseErr
 						}
 						e, err := strconv.Atoi(entAndPos[2])
 						if err != nil {
-							return []NumberAcharyaEntity{}, err
+							parseErr.Column += len(entAndPos[0]) + len(entAndPos[1]) + 2
+							parseErr.Err = err
+							return []NumberAcharyaEntity{}, parseErr
 						}
 
-						annotationNo, err := GetTextAnnNum(scanner.Text())
+						annotationNo, err := GetTextAnnNum(line)
 						if err != nil {
+							var annErr *ParseError
+							if errors.As(err, &annErr) {
+								annErr.Path = readerName(aData)
+								annErr.Line = lineNo
+							}
 							return []NumberAcharyaEntity{}, err
 						}
 
 						numberEntityArr = append(numberEntityArr, NumberAcharyaEntity{annotationNo, AcharyaEntity{b, e, entAndPos[0]}})
 					}
 				} else {
-					return numberEntityArr, errors.New(ErrBadFormat)
+					parseErr.Kind = ErrParseBadFormat
+					parseErr.Err = badFormatDetail(splitAnn[1])
+					return numberEntityArr, parseErr
 				}
 			} else {
-				return numberEntityArr, errors.New(ErrBadFormatTab)
+				parseErr.Kind = ErrParseBadFormatTab
+				return numberEntityArr, parseErr
 			}
 		}
 	}
@@ -222,8 +371,29 @@ func GenNumberEntityArr(entFromConf map[string]bool, aData *os.File) ([]NumberAc
 	return numberEntityArr, nil
 }
 
+// RecordMeta is written to the "Meta" field of an Acharya record
+type RecordMeta struct {
+	Conf string `json:"conf,omitempty"`
+	// SourceDoc and SourceOffset locate a segment of a document split with `--segment`
+	SourceDoc    string `json:"source_doc,omitempty"`
+	SourceOffset *int   `json:"source_offset,omitempty"`
+	// Window is the index of the window of the document, with `--window`
+	Window *int `json:"window,omitempty"`
+}
+
 func GenerateAcharyaAndStandoff(tData string, numberAcharyaEnt []NumberAcharyaEntity) (string, string, error) {
+	return generateAcharyaAndStandoff(tData, numberAcharyaEnt, nil)
+}
+
+func generateAcharyaAndStandoff(tData string, numberAcharyaEnt []NumberAcharyaEntity, meta *RecordMeta) (string, string, error) {
+	return generateAcharyaRecord(tData, numberAcharyaEnt, meta, OffsetUnitChars)
+}
+
+// generateAcharyaRecord generates the Acharya record and the standoff of the entities, the offsets of the record are
+// counted in offsetUnit
+func generateAcharyaRecord(tData string, numberAcharyaEnt []NumberAcharyaEntity, meta *RecordMeta, offsetUnit string) (string, string, error) {
 	standoff := ""
+	offset := offsetConverter(tData, offsetUnit)
 	// It is necessary to marshal string as to avoid problems by escape sequences
 	escapedStr, err := json.Marshal(tData)
 	if err != nil {
@@ -235,148 +405,663 @@ func GenerateAcharyaAndStandoff(tData string, numberAcharyaEnt []NumberAcharyaEn
 	for _, v := range numberAcharyaEnt {
 		str, err := GetSubString(tData, v.Entity.Begin, v.Entity.End)
 		if err != nil {
-			return "", "", err
+			return "", "", &ParseError{ID: fmt.Sprintf("T%d", v.TxtAnnNo), Kind: ErrParseBadSpan, Err: err}
 		}
 		standoff = standoff + fmt.Sprintf("T%d\t%s %d %d\t%s\n", v.TxtAnnNo, v.Entity.Name, v.Entity.Begin, v.Entity.End, str)
-		acharya = acharya + fmt.Sprintf("[%d,%d,\"%s\"],", v.Entity.Begin, v.Entity.End, v.Entity.Name)
+		acharya = acharya + fmt.Sprintf("[%d,%d,\"%s\"],", offset(v.Entity.Begin), offset(v.Entity.End), v.Entity.Name)
 	}
 
 	standoff = strings.TrimSuffix(standoff, "\n")
 	acharya = strings.TrimSuffix(acharya, ",")
 	acharya = strings.ReplaceAll(acharya, "\n", "\\n")
-	acharya = acharya + "]}\n"
+	acharya = acharya + "]"
+
+	if meta != nil {
+		metaJSON, err := json.Marshal(meta)
+		if err != nil {
+			return "", "", err
+		}
+		acharya = acharya + fmt.Sprintf(",\"Meta\":%s", metaJSON)
+	}
+	acharya = acharya + "}\n"
 
 	return acharya, standoff, nil
 }
 
 func handleOutput(outputFile, acharya string, overWrite bool) error {
-	if !overWrite {
+	return handleOutputStream(outputFile, defaultOutputMode(overWrite), func(w io.Writer) error {
+		_, err := io.WriteString(w, acharya)
+		return err
+	})
+}
+
+// handleOutputStream saves what write writes to outputFile, compressed when its extension is `.gz` or `.zst`
+func handleOutputStream(outputFile string, mode OutputMode, write func(io.Writer) error) error {
+	if !mode.OverWrite {
 		if _, err := os.Stat(outputFile); !os.IsNotExist(err) {
 			return errors.New(ErrFlagFileAlreadyExists)
 		}
 	}
 
-	f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY, 0600)
+	return writeFileAtomicStream(outputFile, mode.Perm, func(w io.Writer) error {
+		return writeCompressed(w, CompressionOf(outputFile), write)
+	})
+}
+
+// OutputMode is how the output files of a run are written
+type OutputMode struct {
+	OverWrite bool
+	// Perm is the permission of the generated files, set by `--file-mode`
+	Perm os.FileMode
+	// Compression compresses the output printed to stdout, set by `--compress`, the files are compressed
+	// following their extension
+	Compression string
+}
+
+// defaultOutputMode writes the files with the default permission and prints the output uncompressed
+func defaultOutputMode(overWrite bool) OutputMode {
+	return OutputMode{OverWrite: overWrite, Perm: defaultFileMode}
+}
+
+// OutputMode returns how the outputs of the conversion are written
+func (o Options) OutputMode() (OutputMode, error) {
+	mode := defaultOutputMode(o.OverWrite)
+	mode.Compression = o.Compress
+	if o.FileMode != "" {
+		perm, err := ParseFileMode(o.FileMode)
+		if err != nil {
+			return mode, err
+		}
+		mode.Perm = perm
+	}
+	return mode, nil
+}
+
+// ParseFileMode parses an octal permission such as `0644`
+func ParseFileMode(mode string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf(ErrValidateFileMode, mode)
+	}
+	return os.FileMode(perm), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames it into place once it is complete,
+// so name is never left truncated or holding the end of its previous content
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicStream(name, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicStream is writeFileAtomic for the content written by write
+func writeFileAtomicStream(name string, perm os.FileMode, write func(io.Writer) error) error {
+	f, err := createAtomicFile(name, perm)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	if err = write(f); err != nil {
+		f.Abort()
+		return err
+	}
+	return f.Commit()
+}
+
+// atomicFile is written to a temporary file next to its name, Commit renames it into place once it is complete
+type atomicFile struct {
+	*os.File
+	name string
+	perm os.FileMode
+}
 
-	if _, err = f.WriteString(acharya); err != nil {
+func createAtomicFile(name string, perm os.FileMode) (*atomicFile, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: tmp, name: name, perm: perm}, nil
+}
+
+// finish syncs and closes the temporary file and gives it the permission of the file, it is removed on failure
+func (f *atomicFile) finish() error {
+	err := f.Sync()
+	if closeErr := f.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.File.Name(), f.perm)
+	}
+	if err != nil {
+		os.Remove(f.File.Name())
+	}
+	return err
+}
+
+// rename moves the finished temporary file to the name of the file
+func (f *atomicFile) rename() error {
+	if err := os.Rename(f.File.Name(), f.name); err != nil {
+		os.Remove(f.File.Name())
 		return err
 	}
+	return nil
+}
+
+// Commit renames the complete file into place
+func (f *atomicFile) Commit() error {
+	if err := f.finish(); err != nil {
+		return err
+	}
+	return f.rename()
+}
+
+// Abort removes the temporary file, the file is left as it was
+func (f *atomicFile) Abort() {
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
+
+// Document is a brat document read from a collection, ready to be written as an Acharya record
+type Document struct {
+	AnnPath  string
+	TxtPath  string
+	Data     string
+	Entities []NumberAcharyaEntity
+	Meta     RecordMeta
+	// Tokenizer and Splitter are those of the tools.conf of the document, the CoNLL output uses them
+	Tokenizer string `json:",omitempty"`
+	Splitter  string `json:",omitempty"`
+}
+
+func readDocument(open func(string) (io.ReadCloser, error), annPath, txtPath string, entities map[string]bool) (*Document, error) {
+	annFile, aErr := open(strings.TrimSpace(annPath))
+	if aErr != nil {
+		return nil, aErr
+	}
+	defer annFile.Close()
+
+	txtFile, tErr := open(strings.TrimSpace(txtPath))
+	if tErr != nil {
+		return nil, tErr
+	}
+	defer txtFile.Close()
+
+	txtFileData, err := ioutil.ReadAll(txtFile)
+	if err != nil {
+		return nil, err
+	}
+
+	entityArr, err := GenNumberEntityArr(entities, annFile)
+	if err != nil {
+		return nil, err
+	}
+
+	annName := readerName(annFile)
+	if annName == "" {
+		annName = annPath
+	}
+	return &Document{AnnPath: annName, TxtPath: strings.TrimSpace(txtPath), Data: string(txtFileData), Entities: entityArr}, nil
+}
+
+// Acharya generates the Acharya record of the document
+func (d *Document) Acharya() (string, error) {
+	return d.AcharyaIn(OffsetUnitChars)
+}
+
+// AcharyaIn generates the Acharya record of the document with its entity offsets counted in offsetUnit
+func (d *Document) AcharyaIn(offsetUnit string) (string, error) {
+	meta := &d.Meta
+	// A document converted without a conf has no metadata to record
+	if d.Meta == (RecordMeta{}) {
+		meta = nil
+	}
+	acharya, _, err := generateAcharyaRecord(d.Data, d.Entities, meta, offsetUnit)
+	if err != nil {
+		var spanErr *ParseError
+		if errors.As(err, &spanErr) {
+			spanErr.Path = d.AnnPath
+		}
+		return "", err
+	}
+	return acharya, nil
+}
 
+// CheckSpans returns a bad span *ParseError for the first entity whose span does not fit the text of the
+// document, the spans have to be checked before the document is segmented or windowed
+func (d *Document) CheckSpans() error {
+	length := len(textChars(d.Data))
+	for _, ent := range d.Entities {
+		var err error
+		switch {
+		case ent.Entity.Begin < 0:
+			err = fmt.Errorf(ErrSubStrNegativeStartPos, ent.Entity.Begin)
+		case ent.Entity.End < ent.Entity.Begin:
+			err = fmt.Errorf(ErrSubStrEndPosSmallerThanStart, ent.Entity.End)
+		case ent.Entity.End > length:
+			err = fmt.Errorf(ErrSubStrEndposGreaterThanDataLen, length, ent.Entity.End)
+		default:
+			continue
+		}
+		return &ParseError{Path: d.AnnPath, ID: fmt.Sprintf("T%d", ent.TxtAnnNo), Kind: ErrParseBadSpan, Err: err}
+	}
 	return nil
 }
 
-func handleMain(fPath, annFiles, txtFiles, conf, opFile string, overwrite bool) error {
-	annMult := []string{}
-	textMult := []string{}
-	var err error
-	if fPath != "" {
-		annMult, textMult, err = GetSubDirectories(fPath)
-		if err != nil {
-			return err
+// convertDocument reads an annotation file and converts it to its records, one per segment or window. The
+// records that cannot be converted are left out and recorded in the failures of the converted document.
+func convertDocument(collection *Collection, opts Options, labelMap *LabelMap, annPath, txtPath, confPath string, entities map[string]bool, tokenizer, splitter string) (*ConvertedDocument, error) {
+	doc, err := readDocument(collection.Open, annPath, txtPath, entities)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Strict {
+		if err = collection.ValidateStandoff(strings.TrimSpace(annPath), doc.Data); err != nil {
+			return nil, err
+		}
+	}
+	converted := &ConvertedDocument{Documents: []*Document{}, Records: []string{}, annPath: doc.AnnPath}
+	if entities == nil {
+		for _, ent := range doc.Entities {
+			converted.Types = append(converted.Types, ent.Entity.Name)
+		}
+	}
+	if confPath != "" {
+		doc.Meta.Conf = collection.ConfLabel(confPath)
+	}
+	doc.Tokenizer, doc.Splitter = tokenizer, splitter
+
+	// The label map is applied first so the overlaps are resolved on the types that are written
+	hierarchy := map[string]string{}
+	if labelMap != nil {
+		if labelMap.NeedsHierarchy() && confPath != "" {
+			if hierarchy, err = collection.Hierarchy(confPath); err != nil {
+				return nil, err
+			}
 		}
+		doc.Entities = labelMap.Apply(doc.Entities, hierarchy)
 	}
 
-	var confPath string
+	if opts.Overlap != "" {
+		var typeOrder []string
+		if confPath != "" {
+			typeOrder, err = collection.EntityOrder(confPath)
+		}
+		if err == nil && labelMap != nil {
+			typeOrder = labelMap.ApplyOrder(typeOrder, hierarchy)
+		}
+		if err == nil {
+			var conflicts []OverlapConflict
+			doc.Entities, conflicts, err = ResolveOverlaps(doc.Entities, opts.Overlap, typeOrder)
+			for _, conflict := range conflicts {
+				conflict.File = doc.AnnPath
+				converted.Conflicts = append(converted.Conflicts, conflict)
+			}
+		}
+		if err != nil {
+			var overlapErr *ParseError
+			if errors.As(err, &overlapErr) {
+				overlapErr.Path = doc.AnnPath
+			}
+			return nil, err
+		}
+	}
 
-	if fPath != "" {
-		// If a folder path is provided then the annotation conf file should be present in the root of the folder
-		confPath = fPath + "/annotation.conf"
-	} else {
-		confPath = conf
+	// The entities have to fit the text before it is cut, an entity outside of it would be mistaken for one
+	// crossing a boundary, and WindowSpans grows a window up to the end of the entities
+	if opts.Segment != "" || opts.Window > 0 {
+		if err = doc.CheckSpans(); err != nil {
+			return nil, err
+		}
+	}
+	docs := []*Document{doc}
+	if opts.Segment != "" {
+		var segmented []SegmentedEntity
+		docs, segmented = doc.Segment(opts.Segment, opts.Crossing, splitter)
+		converted.Segmented = append(converted.Segmented, segmented...)
+	}
+	if opts.Window > 0 {
+		windows := []*Document{}
+		for _, d := range docs {
+			// The windows are numbered across the segments, so the index is unique within the source document
+			for _, window := range d.Windows(opts.Window, opts.Stride, opts.WindowUnit, tokenizer) {
+				index := len(windows)
+				window.Meta.Window = &index
+				windows = append(windows, window)
+			}
+		}
+		docs = windows
 	}
 
-	confFile, cErr := os.Open(confPath)
-	if cErr != nil {
-		return cErr
+	for _, d := range docs {
+		acharya, err := d.AcharyaIn(opts.OffsetUnit)
+		if err != nil {
+			converted.failed = append(converted.failed, err)
+			continue
+		}
+		converted.Documents = append(converted.Documents, d)
+		converted.Records = append(converted.Records, acharya)
 	}
-	defer confFile.Close()
+	return converted, nil
+}
 
-	entities := GetEntitiesFromFile(confFile)
-	if len(entities) == 0 {
-		return errors.New(ErrNoEntities)
+func handleMain(opts Options) error {
+	outputs, err := opts.OutputSpecs()
+	if err != nil {
+		return err
 	}
 
-	if fPath == "" {
-		annMult = strings.Split(annFiles, ",")
-		textMult = strings.Split(txtFiles, ",")
+	mode, err := opts.OutputMode()
+	if err != nil {
+		return err
 	}
 
-	generatedAcharya := ""
+	collection, err := OpenCollection(opts)
+	if err != nil {
+		return err
+	}
 
-	for i := range annMult {
-		annFile, aErr := os.Open(strings.TrimSpace(annMult[i]))
-		if aErr != nil {
-			return aErr
+	if collection.RootConf != "" && !opts.AllTypes {
+		if _, err = collection.Entities(collection.RootConf); err != nil {
+			return err
 		}
-		defer annFile.Close()
+	}
 
-		txtFile, tErr := os.Open(strings.TrimSpace(textMult[i]))
-		if tErr != nil {
-			return tErr
+	var labelMap *LabelMap
+	if opts.LabelMap != "" {
+		if labelMap, err = LoadLabelMap(opts.LabelMap); err != nil {
+			return err
 		}
-		defer txtFile.Close()
+	}
 
-		txtFileData, err := ioutil.ReadAll(txtFile)
+	writesCoNLL := false
+	for _, output := range outputs {
+		writesCoNLL = writesCoNLL || output.Format == FormatCoNLL
+	}
+	// resolveTools returns the tokenizer and sentence splitter of the tools.conf closest to the document, the root
+	// one for an empty annPath, unless the flags set them
+	resolveTools := func(annPath string) (string, string, error) {
+		tools, err := collection.ToolsConf(annPath)
 		if err != nil {
+			return "", "", err
+		}
+		tokenizer, splitter := tools.Resolve(opts.Tokenizer, opts.Splitter)
+		if (opts.Window > 0 && opts.WindowUnit == WindowUnitTokens) || writesCoNLL {
+			if err = ValidateTokenizer(tokenizer); err != nil {
+				return "", "", err
+			}
+		}
+		if opts.Segment == SegmentSentence || writesCoNLL {
+			if err = ValidateSplitter(splitter); err != nil {
+				return "", "", err
+			}
+		}
+		return tokenizer, splitter, nil
+	}
+	tokenizer, splitter, err := resolveTools("")
+	if err != nil {
+		return err
+	}
+
+	annMult := collection.Ann
+	textMult := collection.Txt
+
+	report := NewErrorReport(len(annMult) + len(collection.Unpaired))
+	// skip records the failure of a document with `--keep-going`, otherwise the error is returned
+	skip := func(annPath string, err error) error {
+		if !opts.KeepGoing {
+			return err
+		}
+		report.Add(strings.TrimSpace(annPath), err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	// skipRecord records the failure of a segment or window of a document with `--keep-going`
+	skipRecord := func(annPath string, err error) error {
+		if !opts.KeepGoing {
 			return err
 		}
+		report.AddRecord(strings.TrimSpace(annPath), err)
+		fmt.Fprintln(os.Stderr, err)
+		return nil
+	}
+	for _, file := range collection.Unpaired {
+		skip(file.Path, file)
+	}
 
-		entityArr, err := GenNumberEntityArr(entities, annFile)
+	// Only the documents that changed since the previous run are converted again, the others come from the cache
+	var cache *ConversionCache
+	if opts.CacheDir != "" {
+		options, err := conversionOptions(opts, tokenizer, splitter)
+		if err == nil {
+			cache, err = OpenCache(opts.CacheDir, options)
+		}
 		if err != nil {
+			fmt.Fprintf(os.Stderr, WarnCacheDisabled+"\n", err)
+			cache = nil
+		}
+	}
+
+	// The records are written to the outputs as soon as their document is converted, only `--split` needs every
+	// document before anything is written, to partition them
+	var writers outputWriters
+	converted := []*Document{}
+	records := []string{}
+	if opts.Split == "" {
+		if writers, err = openOutputs(opts, outputs, tokenizer, splitter); err != nil {
 			return err
 		}
+		// Every output is removed when the run fails, it is a no-op once they are committed
+		defer writers.Abort()
+	} else if writesStdout(outputs) {
+		return errors.New(ErrValidateSplitNoOp)
+	}
 
-		acharya, _, err := GenerateAcharyaAndStandoff(string(txtFileData), entityArr)
+	// usedConfs lists the confs of the documents, in the order they are first used, for `--schema`
+	usedConfs := []string{}
+	for i := range annMult {
+		confPath, entities, err := collection.DocumentTypes(strings.TrimSpace(annMult[i]), opts.AllTypes)
 		if err != nil {
-			return err
+			if err = skip(annMult[i], err); err != nil {
+				return err
+			}
+			continue
 		}
 
-		generatedAcharya = generatedAcharya + acharya
+		docTokenizer, docSplitter, err := resolveTools(strings.TrimSpace(annMult[i]))
+		if err != nil {
+			if err = skip(annMult[i], err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := ""
+		var doc *ConvertedDocument
+		if cache != nil {
+			toolsPath := collection.ToolsConfPath(strings.TrimSpace(annMult[i]))
+			if key = cacheKey(cache, collection, strings.TrimSpace(annMult[i]), strings.TrimSpace(textMult[i]), confPath, toolsPath); key != "" {
+				doc = cache.Get(key)
+			}
+		}
+		if doc == nil {
+			if doc, err = convertDocument(collection, opts, labelMap, annMult[i], textMult[i], confPath, entities, docTokenizer, docSplitter); err != nil {
+				if err = skip(annMult[i], err); err != nil {
+					return err
+				}
+				continue
+			}
+			if key != "" && len(doc.failed) == 0 {
+				if err = cache.Put(key, doc); err != nil {
+					fmt.Fprintf(os.Stderr, WarnCacheWrite+"\n", err)
+				}
+			}
+		}
 
+		for _, name := range doc.Types {
+			report.AddType(name)
+		}
+		if confPath != "" && !containsString(usedConfs, confPath) {
+			usedConfs = append(usedConfs, confPath)
+		}
+		for _, conflict := range doc.Conflicts {
+			report.Conflicts = append(report.Conflicts, conflict)
+			fmt.Fprintln(os.Stderr, conflict)
+		}
+		for _, entity := range doc.Segmented {
+			report.Segmented = append(report.Segmented, entity)
+			fmt.Fprintln(os.Stderr, entity)
+		}
+		for _, failure := range doc.failed {
+			if err = skipRecord(doc.annPath, failure); err != nil {
+				return err
+			}
+		}
+		if writers == nil {
+			converted = append(converted, doc.Documents...)
+			records = append(records, doc.Records...)
+			continue
+		}
+		for j, d := range doc.Documents {
+			if err = writers.WriteDocument(d, doc.Records[j]); err != nil {
+				return err
+			}
+		}
 	}
 
-	if opFile == "" {
-		fmt.Println(generatedAcharya)
-		return nil
+	if len(report.Types) > 0 {
+		fmt.Fprintf(os.Stderr, InfoAllTypes+"\n", report.TypeCounts())
 	}
 
-	err = handleOutput(opFile, generatedAcharya, overwrite)
+	if opts.ErrorReport != "" {
+		if err = report.Write(opts.ErrorReport, mode.Perm); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, InfoSuccessfullyGenReport+"\n", opts.ErrorReport)
+	}
+
+	// Every output is rendered from the same documents, parsed once
+	if writers != nil {
+		err = writers.Commit()
+	} else {
+		err = writeSplits(opts, outputs, converted, records, tokenizer, splitter)
+	}
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf(InfoSuccessfullyGenFile, opFile)
+	if opts.Schema != "" {
+		if err = writeCollectionSchema(opts, collection, usedConfs, sortedKeys(report.Types), labelMap); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	return report.Err()
 }
 
-func ValidateFlags(fPath, annFiles, txtFiles, confFile, oFileName string, overWrite bool) error {
-	if len(fPath) == 0 {
+// writeOutput prints output or saves it to outputFile
+func writeOutput(outputFile, output string, mode OutputMode) error {
+	return writeOutputStream(outputFile, mode, func(w io.Writer) error {
+		_, err := io.WriteString(w, output)
+		return err
+	})
+}
+
+func ValidateFlags(opts Options) error {
+	if len(opts.FolderPath) == 0 {
 		switch {
-		case IsEmptyString(annFiles):
+		case IsEmptyString(opts.AnnFiles) && len(opts.Include) == 0 && IsEmptyString(opts.FilesFrom):
 			return errors.New(ErrValidateNoAnnFiles)
-		case IsEmptyString(txtFiles):
-			return errors.New(ErrValidateNoTxtFiles)
-		case IsEmptyString(confFile):
-			return errors.New(ErrValidateNoConfFile)
 		}
 
-		err := ValidateAnnAndTxt(annFiles, txtFiles)
+		// Without `--txt` the .txt files are discovered from the .ann base names
+		if !IsEmptyString(opts.TxtFiles) {
+			if IsEmptyString(opts.AnnFiles) {
+				return errors.New(ErrValidateTxtWithoutAnn)
+			}
+			err := ValidateAnnAndTxt(opts.AnnFiles, opts.TxtFiles)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := ValidateStdin(opts.AnnFiles, opts.TxtFiles, opts.ConfFile, opts.FilesFrom)
 		if err != nil {
 			return err
 		}
-	} else if IsEmptyString(fPath) {
+	} else if IsEmptyString(opts.FolderPath) {
 		return errors.New(ErrValidateEmptyFolder)
 	}
 
-	if overWrite && oFileName == "" {
+	if opts.OverWrite && opts.OutputFile == "" && len(opts.Outputs) == 0 {
 		return errors.New(ErrValidateOutputFileNotFound)
 	}
 
+	outputs, err := opts.OutputSpecs()
+	if err != nil {
+		return err
+	}
+
+	if opts.FileMode != "" {
+		if _, err := ParseFileMode(opts.FileMode); err != nil {
+			return err
+		}
+	}
+
+	if opts.Compress != "" {
+		if err := ValidateCompression(opts.Compress); err != nil {
+			return err
+		}
+	}
+
+	if opts.Overlap != "" {
+		if err := ValidateOverlapStrategy(opts.Overlap); err != nil {
+			return err
+		}
+	}
+
+	if opts.Segment != "" {
+		if err := ValidateSegment(opts.Segment, opts.Crossing); err != nil {
+			return err
+		}
+	}
+
+	if opts.Window != 0 || opts.Stride != 0 {
+		if err := ValidateWindow(opts.Window, opts.Stride, opts.WindowUnit); err != nil {
+			return err
+		}
+	}
+
+	if opts.OffsetUnit != "" {
+		if err := ValidateOffsetUnit(opts.OffsetUnit); err != nil {
+			return err
+		}
+	}
+
+	if opts.Tokenizer != "" {
+		if err := ValidateTokenizer(opts.Tokenizer); err != nil {
+			return err
+		}
+	}
+
+	if opts.Splitter != "" {
+		if err := ValidateSplitter(opts.Splitter); err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateShards(opts, outputs); err != nil {
+		return err
+	}
+
+	if opts.Split != "" {
+		if writesStdout(outputs) {
+			return errors.New(ErrValidateSplitNoOp)
+		}
+		if _, err := ParseSplit(opts.Split); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -391,6 +1076,10 @@ func ValidateAnnAndTxt(ann, txt string) error {
 	for i, annPath := range annArray {
 		annBaseName := strings.TrimSpace(filepath.Base(annPath))
 		txtBaseName := strings.TrimSpace(filepath.Base(txtArray[i]))
+		// A document read from stdin has no name to compare
+		if annBaseName == StdinPath || txtBaseName == StdinPath {
+			continue
+		}
 		if strings.TrimSuffix(annBaseName, filepath.Ext(annBaseName))+".txt" != txtBaseName {
 			return fmt.Errorf(ErrAnnFileNotCorrespondToTxt, annPath, strings.TrimSuffix(annBaseName, filepath.Ext(annBaseName)), txtArray[i])
 		}
@@ -399,17 +1088,92 @@ func ValidateAnnAndTxt(ann, txt string) error {
 	return nil
 }
 
+// ValidateStdin checks that stdin is read for at most one of the inputs of a single document
+func ValidateStdin(annFiles, txtFiles, confFile, filesFrom string) error {
+	isStdin := func(paths string) bool {
+		for _, p := range strings.Split(paths, ",") {
+			if strings.TrimSpace(p) == StdinPath {
+				return true
+			}
+		}
+		return false
+	}
+
+	stdinCount := 0
+	for _, paths := range []string{annFiles, txtFiles, confFile, filesFrom} {
+		if isStdin(paths) {
+			stdinCount++
+		}
+	}
+
+	switch {
+	case stdinCount > 1:
+		return errors.New(ErrValidateMultipleStdin)
+	case (isStdin(annFiles) || isStdin(txtFiles)) && (len(strings.Split(annFiles, ",")) > 1 || filesFrom != ""):
+		return errors.New(ErrValidateStdinMultiDocs)
+	}
+	return nil
+}
+
 func IsEmptyString(s string) bool {
 	return strings.TrimSpace(s) == "" || len(s) <= 0
 }
 
+// commands are run with `brat-standoff-to-json <command> [flags]`, without a command the collection is converted
+var commands = map[string]func(args []string) error{
+	"stats":      runStats,
+	"agreement":  runAgreement,
+	"diff":       runDiff,
+	"merge":      runMerge,
+	"infer-conf": runInferConf,
+}
+
 func main() {
-	folderPath := flag.StringP("folderPath", "p", "", "Path to the folder containing the collection")
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit1()
+			}
+			return
+		}
+	}
+
+	folderPath := flag.StringP("folderPath", "p", "", "Path to the folder (or .zip, .tar, .tar.gz archive) containing the collection, `-` reads a single document from stdin")
 	annFiles := flag.StringP("ann", "a", "", "Comma sepeartad locations of the annotation files (.ann) in correct order")
-	txtFiles := flag.StringP("txt", "t", "", "Comma sepeartad locations of the text files (.txt) in correct order")
-	confFile := flag.StringP("conf", "c", "", "Location of the annotation configuration file (annotation.conf)")
-	oFileName := flag.StringP("output", "o", "", "Name of the output file to be generated")
+	txtFiles := flag.StringP("txt", "t", "", "Comma sepeartad locations of the text files (.txt) in correct order, discovered from the .ann names when not given")
+	include := flag.StringArrayP("include", "i", []string{}, "Glob pattern of the annotation files (.ann) to convert, `**` matches any number of directories. Can be repeated")
+	exclude := flag.StringArrayP("exclude", "x", []string{}, "Glob pattern of the annotation files (.ann) to skip. Can be repeated")
+	filesFrom := flag.String("files-from", "", "File listing the annotation files (.ann) to convert, one per line")
+	confFile := flag.StringP("conf", "c", "", "Location of the annotation configuration file (annotation.conf), every entity type is converted without one")
+	fileMode := flag.String("file-mode", "0600", "Permissions of the generated files, in octal")
+	compress := flag.String("compress", "", "Compress the output printed to stdout: gzip or zstd. Output files ending in .gz or .zst are always compressed")
+	allTypes := flag.Bool("all-types", false, "Convert every entity type, even the ones missing from the [entities] of annotation.conf")
+	outputs := flag.StringArrayP("output", "o", []string{}, "Name of the output file to be generated, `-` or no value writes to stdout. `format=file` writes another format (acharya, conll or spacy), can be repeated")
 	overWrite := flag.BoolP("force", "f", false, "If you wish to overwrite the generated file then set force to true")
+	keepGoing := flag.BoolP("keep-going", "k", false, "Skip documents that fail to convert instead of aborting the whole run")
+	errorReport := flag.StringP("error-report", "e", "", "Name of the JSON file to write the per-document error report to")
+	split := flag.String("split", "", "Ratios to split the documents by, `0.8,0.1,0.1` or `train=0.8,dev=0.1,test=0.1`, one output file is written per split")
+	shardSize := flag.Int("shard-size", 0, "Write the output in numbered shards of at most this many records, with a manifest listing them")
+	shardBytes := flag.String("shard-bytes", "", "Write the output in numbered shards of at most this size, `100MB`, with a manifest listing them")
+	seed := flag.Int64("seed", 42, "Seed of the random split, the same seed always gives the same split")
+	stratify := flag.Bool("stratify", false, "Keep the entity type distribution of every split close to the whole collection")
+	groupByDir := flag.Bool("group-by-dir", false, "Keep the documents of the same directory in the same split")
+	overlap := flag.String("overlap", "", "Resolve overlapping and nested entities: keep-longest, keep-shortest, keep-first, priority (order of [entities]) or fail")
+	segment := flag.String("segment", "", "Write one record per `sentence` or `paragraph` instead of one per document")
+	crossing := flag.String("crossing", CrossingDrop, "What to do with the entities crossing a segment boundary: drop or split")
+	window := flag.Int("window", 0, "Write the documents as windows of at most this many characters or tokens, entities are never cut")
+	stride := flag.Int("stride", 0, "Number of characters or tokens between the start of two windows, the window size by default")
+	windowUnit := flag.String("window-unit", WindowUnitChars, "Unit of --window and --stride: chars or tokens")
+	offsetUnit := flag.String("offset-unit", OffsetUnitChars, "Unit of the entity offsets of the Acharya records: chars, bytes (UTF-8) or utf16")
+	strict := flag.Bool("strict", false, "Validate every annotation of the .ann files and check the text of the text-bound annotations against the .txt")
+	tokenizer := flag.String("tokenizer", "", "Tokenizer of --window-unit tokens: whitespace or ptblike. Defaults to the one of tools.conf, or whitespace")
+	splitter := flag.String("splitter", "", "Sentence splitter of --segment sentence: regex or newline. Defaults to the one of tools.conf, or regex")
+	schema := flag.String("schema", "", "Write the entity types with their display names and colours from visual.conf, as a Label Studio config for .xml files, as JSON otherwise")
+	labelMap := flag.String("label-map", "", "JSON or YAML file renaming, merging, dropping or collapsing the entity types")
+	cacheDir := flag.String("cache-dir", "", "Directory of the cache of the converted documents, only the documents that changed are converted again, no cache by default")
+	noCache := flag.Bool("no-cache", false, "Convert every document, without reading or writing the cache")
+	config := flag.String("config", "", "Project file (bratconv.yaml or bratconv.toml) holding default flag values, bratconv.* in --folderPath is used when not given")
 	version := flag.BoolP("version", "v", false, "Print bratconverter version")
 
 	flag.Parse()
@@ -419,15 +1183,70 @@ func main() {
 		exit1()
 	}
 
-	err := ValidateFlags(*folderPath, *annFiles, *txtFiles, *confFile, *oFileName, *overWrite)
+	// The settings of the project file fill in the flags that were not given
+	projectFile := *config
+	if projectFile == "" {
+		projectFile = FindProjectFile(*folderPath)
+	}
+	if projectFile != "" {
+		if err := ApplyProjectFile(flag.CommandLine, projectFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit1()
+		}
+	}
+
+	opts := Options{
+		FolderPath:  *folderPath,
+		AnnFiles:    *annFiles,
+		TxtFiles:    *txtFiles,
+		ConfFile:    *confFile,
+		Outputs:     *outputs,
+		OverWrite:   *overWrite,
+		KeepGoing:   *keepGoing,
+		ErrorReport: *errorReport,
+		Include:     *include,
+		Exclude:     *exclude,
+		FilesFrom:   *filesFrom,
+		Split:       *split,
+		Seed:        *seed,
+		Stratify:    *stratify,
+		GroupByDir:  *groupByDir,
+		LabelMap:    *labelMap,
+		Overlap:     *overlap,
+		Segment:     *segment,
+		Crossing:    *crossing,
+		Window:      *window,
+		Stride:      *stride,
+		WindowUnit:  *windowUnit,
+		OffsetUnit:  *offsetUnit,
+		Strict:      *strict,
+		Schema:      *schema,
+		Tokenizer:   *tokenizer,
+		Splitter:    *splitter,
+		AllTypes:    *allTypes,
+		FileMode:    *fileMode,
+		Compress:    *compress,
+		ShardSize:   *shardSize,
+		ShardBytes:  *shardBytes,
+		CacheDir:    *cacheDir,
+	}
+	if *noCache {
+		opts.CacheDir = ""
+	}
+
+	err := ValidateFlags(opts)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		exit1()
 	}
 
-	err = handleMain(*folderPath, *annFiles, *txtFiles, *confFile, *oFileName, *overWrite)
+	err = handleMain(opts)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		var failed *DocumentsFailedError
+		if errors.As(err, &failed) {
+			exit2()
+		}
 		exit1()
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -135,11 +136,18 @@ func (suite *GeneratePathsSuite) SetupTest() {
 		},
 	}
 
-	suite.TestDataInvalid = []GeneratePathsTest{
+	suite.TestData = append(suite.TestData, []GeneratePathsTest{
 		{"./testData/invalid-files/multiple-ann",
 			[]string{},
 			[]string{},
 		},
+		{"./testData/nested-conf",
+			[]string{"testData/nested-conf/000-introduction.ann", "testData/nested-conf/organizations/000-introduction.ann"},
+			[]string{"testData/nested-conf/000-introduction.txt", "testData/nested-conf/organizations/000-introduction.txt"},
+		},
+	}...)
+
+	suite.TestDataInvalid = []GeneratePathsTest{
 		{"./testData/invalid-files/no-corresponding-ann",
 			[]string{},
 			[]string{},
@@ -156,6 +164,7 @@ func (suite *GeneratePathsSuite) TestGetSubDirectories() {
 	for _, v := range suite.TestData {
 		anns, txts, err := GetSubDirectories(v.Input)
 		suite.Nil(err)
+		suite.Equal(len(v.ExpectedAnn), len(anns))
 
 		for i, ann := range anns {
 			suite.Equal(v.ExpectedAnn[i], filepath.ToSlash(ann))
@@ -177,6 +186,22 @@ func (suite *GeneratePathsSuite) TestGetSubDirectoriesInvalid() {
 	}
 }
 
+func (suite *GeneratePathsSuite) TestGetNearestConf() {
+	confPath, err := GetNearestConf("./testData/nested-conf", "testData/nested-conf/organizations/000-introduction.ann")
+	suite.Nil(err)
+	suite.Equal("testData/nested-conf/organizations/annotation.conf", filepath.ToSlash(confPath))
+
+	confPath, err = GetNearestConf("./testData/nested-conf", "testData/nested-conf/000-introduction.ann")
+	suite.Nil(err)
+	suite.Equal("testData/nested-conf/annotation.conf", filepath.ToSlash(confPath))
+
+	// The lookup never leaves the collection
+	_, err = GetNearestConf("./testData/invalid-files/no-corresponding-txt/", "testData/invalid-files/no-corresponding-txt/010-navigation.txt")
+	suite.Nil(err)
+	_, err = GetNearestConf("./testData/news/", "testData/README")
+	suite.NotNil(err)
+}
+
 type GetTextAnnNoTest struct {
 	Input    string
 	Expected int
@@ -435,30 +460,31 @@ func (suite *ValidateFlagsSuite) SetupTest() {
 	suite.TestData = []ValidateFlagsTest{
 		{Input: TestInput{"./testData/", "a.ann,b.ann", "a.txt,b.txt", "./annotation.conf", "OfileName", true}},
 		{Input: TestInput{"", "a.ann,b.ann", "a.txt,b.txt", "./annotation.conf", "OfileName", true}},
+		// The .txt files are discovered from the .ann files, and the entity types from the annotations
+		{Input: TestInput{"", "a.ann,b.ann", "", "./annotation.conf", "OfileName", true}},
+		{Input: TestInput{"", "a.ann,b.ann", "a.txt,b.txt", "", "OfileName", true}},
 	}
 
 	suite.TestDataInvalid = []ValidateFlagsTest{
 		{Input: TestInput{" ", "a.ann,b.ann", "a.txt,b.txt", "./annotation.conf", "OfileName", true}},
 		{Input: TestInput{"./testData/", "a.ann,b.ann", "a.txt,b.txt", "./annotation.conf", "", true}},
-		{Input: TestInput{"", "", "a.txt,b.txt", "./annotation.conf", "", true}},
-		{Input: TestInput{"", "a.ann,b.ann", "", "./annotation.conf", "", true}},
-		{Input: TestInput{"", "a.ann,b.ann", "a.txt,b.txt", "", "", true}},
+		{Input: TestInput{"", "", "a.txt,b.txt", "./annotation.conf", "OfileName", true}},
 
-		{Input: TestInput{"", "a.ann,b.ann", "a.txt", "./annotation.conf", "", true}},
-		{Input: TestInput{"", "a.ann,b.ann", "a.txt,c.txt", "./annotation.conf", "", true}},
+		{Input: TestInput{"", "a.ann,b.ann", "a.txt", "./annotation.conf", "OfileName", true}},
+		{Input: TestInput{"", "a.ann,b.ann", "a.txt,c.txt", "./annotation.conf", "OfileName", true}},
 	}
 }
 
 func (suite *ValidateFlagsSuite) TestValidateFlags() {
 	for _, v := range suite.TestData {
-		err := ValidateFlags(v.Input.FPath, v.Input.AnnFiles, v.Input.TxtFiles, v.Input.ConfFile, v.Input.OFileName, v.Input.OverWrite)
+		err := ValidateFlags(Options{FolderPath: v.Input.FPath, AnnFiles: v.Input.AnnFiles, TxtFiles: v.Input.TxtFiles, ConfFile: v.Input.ConfFile, OutputFile: v.Input.OFileName, OverWrite: v.Input.OverWrite})
 		suite.Nil(err)
 	}
 }
 
 func (suite *ValidateFlagsSuite) TestValidateFlagsInvalid() {
 	for _, v := range suite.TestDataInvalid {
-		err := ValidateFlags(v.Input.FPath, v.Input.AnnFiles, v.Input.TxtFiles, v.Input.ConfFile, v.Input.OFileName, v.Input.OverWrite)
+		err := ValidateFlags(Options{FolderPath: v.Input.FPath, AnnFiles: v.Input.AnnFiles, TxtFiles: v.Input.TxtFiles, ConfFile: v.Input.ConfFile, OutputFile: v.Input.OFileName, OverWrite: v.Input.OverWrite})
 		suite.NotNil(err)
 	}
 }
@@ -517,18 +543,40 @@ func (suite *HandleMainTestSuite) SetupTest() {
 
 func (suite *HandleMainTestSuite) TestHandleMainTest() {
 	for _, v := range suite.TestData {
-		err := handleMain(v.Input.FPath, v.Input.AnnFiles, v.Input.TxtFiles, v.Input.ConfFile, v.Input.OFileName, v.Input.OverWrite)
+		err := handleMain(Options{FolderPath: v.Input.FPath, AnnFiles: v.Input.AnnFiles, TxtFiles: v.Input.TxtFiles, ConfFile: v.Input.ConfFile, OutputFile: v.Input.OFileName, OverWrite: v.Input.OverWrite})
 		suite.Nil(err)
 	}
 }
 
 func (suite *HandleMainTestSuite) TestHandleMainTestInvalid() {
 	for _, v := range suite.TestDataInvalid {
-		err := handleMain(v.Input.FPath, v.Input.AnnFiles, v.Input.TxtFiles, v.Input.ConfFile, v.Input.OFileName, v.Input.OverWrite)
+		err := handleMain(Options{FolderPath: v.Input.FPath, AnnFiles: v.Input.AnnFiles, TxtFiles: v.Input.TxtFiles, ConfFile: v.Input.ConfFile, OutputFile: v.Input.OFileName, OverWrite: v.Input.OverWrite})
 		suite.NotNil(err, fmt.Sprint(v))
 	}
 }
 
+func (suite *HandleMainTestSuite) TestHandleMainNestedConf() {
+	dir, err := ioutil.TempDir("", "nested-conf")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "out.jsonl")
+	err = handleMain(Options{FolderPath: "./testData/nested-conf", OutputFile: output})
+	suite.Nil(err)
+
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	records := strings.Split(strings.TrimSpace(string(data)), "\n")
+	suite.Equal(2, len(records))
+
+	suite.Contains(records[0], `[473,496,"Person"]`)
+	suite.Contains(records[0], `"Meta":{"conf":"annotation.conf"}`)
+
+	suite.NotContains(records[1], `"Person"`)
+	suite.Contains(records[1], `[418,426,"Organization"],[540,545,"Organization"]]`)
+	suite.Contains(records[1], `"Meta":{"conf":"organizations/annotation.conf"}`)
+}
+
 func TestHandleOutput(t *testing.T) {
 	e := os.Remove("./testData/file_gen_by_handleOutputTest.jsonl")
 	assert.Nil(t, e)
@@ -554,6 +602,47 @@ func TestHandleOutput(t *testing.T) {
 
 }
 
+func TestHandleOutputAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "handle-output")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Overwriting a longer file leaves none of its content behind
+	output := filepath.Join(dir, "out.jsonl")
+	assert.Nil(t, handleOutput(output, strings.Repeat("stale\n", 100), false))
+	assert.Nil(t, writeOutput(output, "fresh\n", OutputMode{OverWrite: true, Perm: 0644}))
+
+	data, err := ioutil.ReadFile(output)
+	assert.Nil(t, err)
+	assert.Equal(t, "fresh\n", string(data))
+	info, err := os.Stat(output)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// The temporary files are renamed into place
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	assert.NotNil(t, handleOutput(filepath.Join(dir, "missing", "out.jsonl"), "fresh\n", true))
+
+	// `--file-mode` of a run does not leak into the next one
+	converted := filepath.Join(dir, "converted.jsonl")
+	assert.Nil(t, handleMain(Options{FolderPath: "./testData/news", OutputFile: converted, FileMode: "0644"}))
+	assert.Nil(t, handleMain(Options{FolderPath: "./testData/news", OutputFile: converted, OverWrite: true}))
+	info, err = os.Stat(converted)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	mode, err := ParseFileMode("0640")
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), mode)
+	_, err = ParseFileMode("rw-r--r--")
+	assert.EqualError(t, err, "invalid file mode: rw-r--r--, expected octal permissions such as `0644`")
+	_, err = ParseFileMode("01777")
+	assert.NotNil(t, err)
+}
+
 func TestRunAllSuites(t *testing.T) {
 	suite.Run(t, new(GetSubStringSuite))
 	suite.Run(t, new(GetEntitiesFromFileSuite))
@@ -563,5 +652,30 @@ func TestRunAllSuites(t *testing.T) {
 	suite.Run(t, new(GenerateAcharyaAndStandoffSuite))
 	suite.Run(t, new(ValidateFlagsSuite))
 	suite.Run(t, new(HandleMainTestSuite))
+	suite.Run(t, new(KeepGoingSuite))
+	suite.Run(t, new(ParseErrorSuite))
+	suite.Run(t, new(ArchiveSuite))
+	suite.Run(t, new(StdinSuite))
+	suite.Run(t, new(GlobSuite))
+	suite.Run(t, new(StandoffSuite))
+	suite.Run(t, new(StatsSuite))
+	suite.Run(t, new(SplitSuite))
+	suite.Run(t, new(AgreementSuite))
+	suite.Run(t, new(DiffSuite))
+	suite.Run(t, new(MergeSuite))
+	suite.Run(t, new(LabelMapSuite))
+	suite.Run(t, new(OverlapSuite))
+	suite.Run(t, new(SegmentSuite))
+	suite.Run(t, new(WindowSuite))
+	suite.Run(t, new(VisualSuite))
+	suite.Run(t, new(ToolsSuite))
+	suite.Run(t, new(InferSuite))
+	suite.Run(t, new(AllTypesSuite))
+	suite.Run(t, new(ProjectSuite))
+	suite.Run(t, new(OutputsSuite))
+	suite.Run(t, new(CompressSuite))
+	suite.Run(t, new(ShardSuite))
+	suite.Run(t, new(CacheSuite))
+	suite.Run(t, new(OffsetSuite))
 
 }
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	ErrMergeRoots          = "the merge command needs at least two collections, received %d"
+	ErrMergeUnknownFormat  = "unknown merge format: %s, expected `standoff` or `json`"
+	ErrMergeNoOutputDir    = "`--format standoff` writes one .ann and .txt file per document, specify the output folder with `--output`"
+	ErrMergeTextMismatched = "the text of %s differs between %s and %s"
+)
+
+// standoffMerger renumbers the annotations of several layers of the same document into a single Standoff
+type standoffMerger struct {
+	merged *Standoff
+	next   map[string]int
+	// seen maps the annotations already merged, without their ID, to their new ID
+	seen map[string]string
+}
+
+func newStandoffMerger() *standoffMerger {
+	return &standoffMerger{merged: &Standoff{}, next: make(map[string]int), seen: make(map[string]string)}
+}
+
+// idPrefix returns the letters of an annotation ID before its number
+func idPrefix(id string) string {
+	return strings.TrimRight(id, "0123456789")
+}
+
+func (m *standoffMerger) newID(prefix string) string {
+	// The older `M` attributes are written as `A`
+	if prefix == "M" {
+		prefix = "A"
+	}
+	m.next[prefix]++
+	return fmt.Sprintf("%s%d", prefix, m.next[prefix])
+}
+
+// MergeStandoff merges the annotation layers of a document. Text-bound annotations with the same type and spans,
+// and annotations identical once their references are rewritten, are only kept once.
+func MergeStandoff(layers []*Standoff) *Standoff {
+	m := newStandoffMerger()
+	for _, layer := range layers {
+		m.add(layer)
+	}
+	return m.merged
+}
+
+func (m *standoffMerger) add(layer *Standoff) {
+	ids := make(map[string]string)
+	// ref returns the new ID of an annotation of the layer, annotations referenced before they are merged get a new ID
+	ref := func(id string) string {
+		if newID, ok := ids[id]; ok {
+			return newID
+		}
+		ids[id] = m.newID(idPrefix(id))
+		return ids[id]
+	}
+	refArgs := func(args []Argument) []Argument {
+		rewritten := []Argument{}
+		for _, arg := range args {
+			rewritten = append(rewritten, Argument{arg.Role, ref(arg.ID)})
+		}
+		return rewritten
+	}
+	// dedupe returns true when an identical annotation has already been merged, it is compared on its line
+	// without its ID
+	dedupe := func(kind, id, line string) bool {
+		key := kind + "\t" + strings.SplitN(line, "\t", 2)[1]
+		if existing, ok := m.seen[key]; ok {
+			if _, referenced := ids[id]; !referenced {
+				ids[id] = existing
+				return true
+			}
+		}
+		m.seen[key] = ref(id)
+		return false
+	}
+
+	for _, tb := range layer.TextBounds {
+		if dedupe("T", tb.ID, tb.Line()) {
+			continue
+		}
+		tb.ID = ref(tb.ID)
+		m.merged.TextBounds = append(m.merged.TextBounds, tb)
+	}
+	for _, r := range layer.Relations {
+		r.Args = refArgs(r.Args)
+		if dedupe("R", r.ID, r.Line()) {
+			continue
+		}
+		r.ID = ref(r.ID)
+		m.merged.Relations = append(m.merged.Relations, r)
+	}
+	for _, e := range layer.Events {
+		e.Trigger = ref(e.Trigger)
+		e.Args = refArgs(e.Args)
+		if dedupe("E", e.ID, e.Line()) {
+			continue
+		}
+		e.ID = ref(e.ID)
+		m.merged.Events = append(m.merged.Events, e)
+	}
+	for _, a := range layer.Attributes {
+		a.Target = ref(a.Target)
+		if dedupe("A", a.ID, a.Line()) {
+			continue
+		}
+		a.ID = ref(a.ID)
+		m.merged.Attributes = append(m.merged.Attributes, a)
+	}
+	for _, n := range layer.Normalizations {
+		n.Target = ref(n.Target)
+		if dedupe("N", n.ID, n.Line()) {
+			continue
+		}
+		n.ID = ref(n.ID)
+		m.merged.Normalizations = append(m.merged.Normalizations, n)
+	}
+	for _, n := range layer.Notes {
+		n.Target = ref(n.Target)
+		if dedupe("#", n.ID, n.Line()) {
+			continue
+		}
+		n.ID = ref(n.ID)
+		m.merged.Notes = append(m.merged.Notes, n)
+	}
+	for _, e := range layer.Equivs {
+		rewritten := Equiv{Type: e.Type}
+		for _, id := range e.IDs {
+			rewritten.IDs = append(rewritten.IDs, ref(id))
+		}
+		if _, ok := m.seen[rewritten.Line()]; ok {
+			continue
+		}
+		m.seen[rewritten.Line()] = ""
+		m.merged.Equivs = append(m.merged.Equivs, rewritten)
+	}
+}
+
+// MergedDocument is a document annotated in at least one of the merged collections
+type MergedDocument struct {
+	Name     string
+	Text     string
+	Standoff *Standoff
+	// Entities holds the `[entities]` of the conf of every layer, nil keeps every type when a layer has no conf
+	Entities map[string]bool
+}
+
+// MergeCollections merges the layers of every document, documents are paired by their path relative to the
+// collection roots
+func MergeCollections(roots []string, collections []*Collection) ([]*MergedDocument, error) {
+	if len(collections) < 2 {
+		return nil, fmt.Errorf(ErrMergeRoots, len(collections))
+	}
+
+	docs := []*MergedDocument{}
+	byName := make(map[string]*MergedDocument)
+	layers := make(map[string][]*Standoff)
+
+	for i, collection := range collections {
+		for j, name := range collection.DocumentNames(roots[i]) {
+			text, err := collection.ReadFile(collection.Txt[j])
+			if err != nil {
+				return nil, err
+			}
+			_, entities, err := collection.DocumentTypes(collection.Ann[j], false)
+			if err != nil {
+				return nil, err
+			}
+			standoff, err := collection.ReadStandoff(collection.Ann[j])
+			if err != nil {
+				return nil, err
+			}
+
+			doc, ok := byName[name]
+			if !ok {
+				doc = &MergedDocument{Name: name, Text: string(text), Entities: make(map[string]bool)}
+				byName[name] = doc
+				docs = append(docs, doc)
+			} else if doc.Text != string(text) {
+				return nil, fmt.Errorf(ErrMergeTextMismatched, name, roots[0], roots[i])
+			}
+			switch {
+			case entities == nil:
+				// A layer without an `annotation.conf` keeps every type
+				doc.Entities = nil
+			case doc.Entities != nil:
+				for entity := range entities {
+					doc.Entities[entity] = true
+				}
+			}
+			layers[name] = append(layers[name], standoff)
+		}
+	}
+
+	for _, doc := range docs {
+		doc.Standoff = MergeStandoff(layers[doc.Name])
+	}
+	return docs, nil
+}
+
+// Acharya converts the merged document the same way a document read from a collection is converted
+func (d *MergedDocument) Acharya() (string, error) {
+	entities, err := GenNumberEntityArr(d.Entities, strings.NewReader(d.Standoff.String()))
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.Path = d.Name
+		}
+		return "", err
+	}
+	doc := &Document{AnnPath: d.Name, Data: d.Text, Entities: entities}
+	return doc.Acharya()
+}
+
+// writeMergedStandoff writes the .txt and merged .ann file of every document under outputDir
+func writeMergedStandoff(outputDir string, docs []*MergedDocument, conf string, overWrite bool) error {
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		return err
+	}
+	if conf != "" {
+		if err := writeOutput(filepath.Join(outputDir, "annotation.conf"), conf, defaultOutputMode(overWrite)); err != nil {
+			return err
+		}
+	}
+	for _, doc := range docs {
+		annPath := filepath.Join(outputDir, filepath.FromSlash(doc.Name))
+		if err := os.MkdirAll(filepath.Dir(annPath), 0700); err != nil {
+			return err
+		}
+		if err := writeOutput(TxtForAnn(annPath), doc.Text, defaultOutputMode(overWrite)); err != nil {
+			return err
+		}
+		if err := writeOutput(annPath, doc.Standoff.String(), defaultOutputMode(overWrite)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergedConfSection is a `[section]` of the merged conf, with the names of the types it defines
+type mergedConfSection struct {
+	header string
+	lines  []string
+	added  []string
+	names  map[string]bool
+}
+
+// MergeConfs returns the union of the `annotation.conf` of the layers: the first conf as it is, followed in every
+// section by the types only defined by the next ones. Those are added at the top level of the `[entities]`
+// hierarchy, their parents may not exist in the first conf.
+func MergeConfs(confs []io.Reader) (string, error) {
+	sections := []*mergedConfSection{}
+	bySection := make(map[string]*mergedConfSection)
+	section := func(header string) *mergedConfSection {
+		if _, ok := bySection[header]; !ok {
+			bySection[header] = &mergedConfSection{header: header, names: make(map[string]bool)}
+			sections = append(sections, bySection[header])
+		}
+		return bySection[header]
+	}
+
+	for i, conf := range confs {
+		current := section("")
+		scanner := bufio.NewScanner(conf)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+				current = section(line)
+				continue
+			}
+			if i == 0 {
+				current.lines = append(current.lines, scanner.Text())
+			}
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			name := strings.Fields(line)[0]
+			if i > 0 && !current.names[name] {
+				current.added = append(current.added, line)
+			}
+			current.names[name] = true
+		}
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+	}
+
+	b := &strings.Builder{}
+	for _, s := range sections {
+		lines := s.lines
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, s.added...)
+		if s.header == "" && len(lines) == 0 {
+			continue
+		}
+		if s.header != "" {
+			b.WriteString(s.header + "\n")
+		}
+		for _, line := range lines {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// runMerge implements the `merge` command
+func runMerge(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	include := flags.StringArrayP("include", "i", []string{}, "Glob pattern of the annotation files (.ann) to merge, relative to every collection. Can be repeated")
+	exclude := flags.StringArrayP("exclude", "x", []string{}, "Glob pattern of the annotation files (.ann) to skip. Can be repeated")
+	format := flags.String("format", "standoff", "Output format: standoff (a folder of .ann and .txt files) or json (Acharya JSONL)")
+	oFileName := flags.StringP("output", "o", "", "Folder to write the merged collection to, or name of the JSONL file to be generated")
+	overWrite := flags.BoolP("force", "f", false, "If you wish to overwrite the generated files then set force to true")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	roots := flags.Args()
+	if len(roots) < 2 {
+		return fmt.Errorf(ErrMergeRoots, len(roots))
+	}
+	if *format != "standoff" && *format != "json" {
+		return fmt.Errorf(ErrMergeUnknownFormat, *format)
+	}
+	if *format == "standoff" && (*oFileName == "" || *oFileName == StdinPath) {
+		return errors.New(ErrMergeNoOutputDir)
+	}
+
+	collections := []*Collection{}
+	for _, root := range roots {
+		collection, err := OpenCollection(Options{FolderPath: root, Include: *include, Exclude: *exclude})
+		if err != nil {
+			return err
+		}
+		collections = append(collections, collection)
+	}
+
+	docs, err := MergeCollections(roots, collections)
+	if err != nil {
+		return err
+	}
+
+	if *format == "standoff" {
+		// The merged collection gets the union of the root confs of the collections
+		confs := []io.Reader{}
+		for _, collection := range collections {
+			if collection.RootConf == "" {
+				continue
+			}
+			data, err := collection.ReadFile(collection.RootConf)
+			if err != nil {
+				return err
+			}
+			confs = append(confs, bytes.NewReader(data))
+		}
+		conf := ""
+		if len(confs) > 0 {
+			if conf, err = MergeConfs(confs); err != nil {
+				return err
+			}
+		}
+		return writeMergedStandoff(*oFileName, docs, conf, *overWrite)
+	}
+
+	records := ""
+	for _, doc := range docs {
+		record, err := doc.Acharya()
+		if err != nil {
+			return err
+		}
+		records = records + record
+	}
+	return writeOutput(*oFileName, records, defaultOutputMode(*overWrite))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MergeSuite struct {
+	suite.Suite
+}
+
+func (suite *MergeSuite) parse(ann string) *Standoff {
+	standoff, err := ParseStandoff(strings.NewReader(ann))
+	suite.Nil(err)
+	return standoff
+}
+
+func (suite *MergeSuite) TestMergeStandoff() {
+	people := suite.parse("T1\tPerson 0 4\tJohn\nT2\tPerson 9 13\tMary\nR1\tFamily Arg1:T1 Arg2:T2\t\nA1\tIndividual T2\n")
+	places := suite.parse("T1\tGPE 17 23\tLondon\nT2\tPerson 0 4\tJohn\nR1\tLocated Arg1:T2 Arg2:T1\t\n" +
+		"E1\tTravel:T3 Who:T2 To:T1\nT3\tTravel 5 8\twent\nA1\tIndividual T2\n#1\tAnnotatorNotes E1\tcheck\n")
+
+	merged := MergeStandoff([]*Standoff{people, places})
+	suite.Equal("T1\tPerson 0 4\tJohn\n"+
+		"T2\tPerson 9 13\tMary\n"+
+		"T3\tGPE 17 23\tLondon\n"+
+		"T4\tTravel 5 8\twent\n"+
+		"R1\tFamily Arg1:T1 Arg2:T2\t\n"+
+		"R2\tLocated Arg1:T1 Arg2:T3\t\n"+
+		"E1\tTravel:T4 Who:T1 To:T3\n"+
+		"A1\tIndividual T2\n"+
+		"A2\tIndividual T1\n"+
+		"#1\tAnnotatorNotes E1\tcheck\n", merged.String())
+
+	// Merging a layer with itself changes nothing
+	suite.Equal(people.String(), MergeStandoff([]*Standoff{people, people}).String())
+}
+
+func (suite *MergeSuite) TestMergeConfs() {
+	conf, err := MergeConfs([]io.Reader{
+		strings.NewReader("# people\n[entities]\nPerson\n\tArtist\n\n[relations]\nKnows\tArg1:Person, Arg2:Person\n"),
+		strings.NewReader("[entities]\nPerson\nPlace\n\tCity\n[attributes]\nNegated\tArg:<ENTITY>\n"),
+	})
+	suite.Nil(err)
+	suite.Equal("# people\n\n[entities]\nPerson\n\tArtist\nPlace\nCity\n\n[relations]\nKnows\tArg1:Person, Arg2:Person\n\n"+
+		"[attributes]\nNegated\tArg:<ENTITY>\n\n", conf)
+}
+
+func (suite *MergeSuite) TestMergeCollections() {
+	dir, err := ioutil.TempDir("", "merge")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	layer := filepath.Join(dir, "gpe")
+	suite.Nil(os.Mkdir(layer, 0700))
+	txt, err := ioutil.ReadFile("./testData/news/000-introduction.txt")
+	suite.Nil(err)
+	suite.Nil(ioutil.WriteFile(filepath.Join(layer, "000-introduction.txt"), txt, 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(layer, "000-introduction.ann"), []byte("T1\tGPE 549 560\tSwitzerland\nT2\tCountry 549 560\tSwitzerland\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(layer, "annotation.conf"), []byte("[entities]\nCountry\n"), 0600))
+
+	roots := []string{"./testData/news", layer}
+	collections := []*Collection{}
+	for _, root := range roots {
+		collection, err := OpenCollection(Options{FolderPath: root, Include: []string{"000-*"}})
+		suite.Nil(err)
+		collections = append(collections, collection)
+	}
+
+	docs, err := MergeCollections(roots, collections)
+	suite.Nil(err)
+	suite.Len(docs, 1)
+	suite.Len(docs[0].Standoff.TextBounds, 8)
+	suite.True(docs[0].Entities["Country"])
+	suite.True(docs[0].Entities["Person"])
+
+	record, err := docs[0].Acharya()
+	suite.Nil(err)
+	suite.Contains(record, "[549,560,\"GPE\"],[549,560,\"Country\"]]")
+
+	output := filepath.Join(dir, "merged")
+	suite.Nil(runMerge(append(roots, "--include", "000-*", "--output", output)))
+	ann, err := ioutil.ReadFile(filepath.Join(output, "000-introduction.ann"))
+	suite.Nil(err)
+	suite.Equal(docs[0].Standoff.String(), string(ann))
+	conf, err := os.Open(filepath.Join(output, "annotation.conf"))
+	suite.Nil(err)
+	entities, err := ParseEntities(conf)
+	conf.Close()
+	suite.Nil(err)
+	suite.True(entities["Country"])
+	suite.True(entities["Person"])
+
+	// A layer without annotation.conf keeps every type
+	suite.Nil(os.Remove(filepath.Join(layer, "annotation.conf")))
+	collections[1], err = OpenCollection(Options{FolderPath: layer})
+	suite.Nil(err)
+	docs, err = MergeCollections(roots, collections)
+	suite.Nil(err)
+	suite.Nil(docs[0].Entities)
+	record, err = docs[0].Acharya()
+	suite.Nil(err)
+	suite.Contains(record, "[443,449,\"Transfer-money\"]")
+
+	_, err = MergeCollections(roots[:1], collections[:1])
+	suite.EqualError(err, "the merge command needs at least two collections, received 1")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	OffsetUnitChars = "chars"
+	OffsetUnitBytes = "bytes"
+	OffsetUnitUTF16 = "utf16"
+
+	ErrOffsetUnknownUnit = "unknown offset unit: %s, expected `chars`, `bytes` or `utf16`"
+)
+
+func ValidateOffsetUnit(unit string) error {
+	switch unit {
+	case OffsetUnitChars, OffsetUnitBytes, OffsetUnitUTF16:
+		return nil
+	}
+	return fmt.Errorf(ErrOffsetUnknownUnit, unit)
+}
+
+// offsetConverter returns the function converting the character offsets of the text to unit: UTF-8 bytes, or
+// UTF-16 code units as JavaScript counts them. The text is counted without `\r`, as the character offsets are.
+func offsetConverter(text, unit string) func(int) int {
+	if unit == "" || unit == OffsetUnitChars {
+		return func(offset int) int { return offset }
+	}
+	chars := textChars(text)
+	offsets := make([]int, len(chars)+1)
+	for i, r := range chars {
+		size := utf8.RuneLen(r)
+		if unit == OffsetUnitUTF16 {
+			// The characters outside of the Basic Multilingual Plane are a surrogate pair
+			size = 1
+			if r > 0xFFFF {
+				size = 2
+			}
+		}
+		offsets[i+1] = offsets[i] + size
+	}
+	return func(offset int) int {
+		if offset < 0 || offset >= len(offsets) {
+			return offset
+		}
+		return offsets[offset]
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OffsetSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *OffsetSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "offsets")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *OffsetSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *OffsetSuite) TestOffsetUnits() {
+	doc := &Document{Data: "Café 😀 Tokyo\r\n", Entities: []NumberAcharyaEntity{{1, AcharyaEntity{7, 12, "GPE"}}}}
+	for unit, expected := range map[string]string{
+		OffsetUnitChars: "[7,12,\"GPE\"]",
+		OffsetUnitBytes: "[11,16,\"GPE\"]",
+		OffsetUnitUTF16: "[8,13,\"GPE\"]",
+	} {
+		record, err := doc.AcharyaIn(unit)
+		suite.Nil(err)
+		suite.Contains(record, expected, unit)
+	}
+	suite.Nil(ValidateOffsetUnit(OffsetUnitUTF16))
+	suite.EqualError(ValidateOffsetUnit("runes"), "unknown offset unit: runes, expected `chars`, `bytes` or `utf16`")
+}
+
+func (suite *OffsetSuite) TestStrict() {
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: "./testData/news", OutputFile: output, Strict: true}))
+
+	suite.Nil(ioutil.WriteFile(filepath.Join(suite.TmpDir, "a.txt"), []byte("Sony is in Tokyo"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(suite.TmpDir, "a.ann"), []byte("T1\tGPE 11 16\tTokyo\nT2\tGPE 0 4\tSoni\n"), 0600))
+	opts := Options{AnnFiles: filepath.Join(suite.TmpDir, "a.ann"), OutputFile: filepath.Join(suite.TmpDir, "a.jsonl")}
+	suite.Nil(handleMain(opts))
+
+	opts.Strict, opts.OverWrite = true, true
+	err := handleMain(opts)
+	var parseErr *ParseError
+	suite.True(errors.As(err, &parseErr))
+	suite.True(errors.Is(err, ErrParseTextMismatch))
+	suite.Equal(2, parseErr.Line)
+	suite.Equal("T2", parseErr.ID)
+	suite.EqualError(err, filepath.Join(suite.TmpDir, "a.ann")+":2: T2: "+ErrTextMismatch+": \"Soni\" in the .ann, \"Sony\" in the .txt")
+}
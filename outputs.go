@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	FormatAcharya = "acharya"
+	FormatCoNLL   = "conll"
+	FormatSpaCy   = "spacy"
+
+	ErrOutputDuplicate    = "%s is written by more than one `--output`"
+	ErrOutputStdoutTwice  = "only one `--output` can write to stdout"
+	ErrOutputUnknownWrite = "unknown output format: %s"
+
+	// conllDocStart starts every document of the CoNLL output, the way CoNLL-2003 separates them
+	conllDocStart = "-DOCSTART-\tO"
+)
+
+var outputFormats = []string{FormatAcharya, FormatCoNLL, FormatSpaCy}
+
+// OutputSpec is an output of the run, written by `--output format=file`
+type OutputSpec struct {
+	Format string
+	File   string
+}
+
+// splitOutputFormat splits the format of an `--output` value from its file, a value without a known format is
+// an Acharya file
+func splitOutputFormat(value string) (string, string) {
+	if i := strings.Index(value, "="); i > 0 && containsString(outputFormats, value[:i]) {
+		return value[:i], value[i+1:]
+	}
+	return FormatAcharya, value
+}
+
+// ParseOutputs parses the `--output` values, `conll=out.conll` or `out.jsonl` for the Acharya records
+func ParseOutputs(values []string) ([]OutputSpec, error) {
+	outputs := []OutputSpec{}
+	files := make(map[string]bool)
+	stdout := false
+	for _, value := range values {
+		format, file := splitOutputFormat(value)
+		if file == "" || file == StdinPath {
+			if stdout {
+				return nil, errors.New(ErrOutputStdoutTwice)
+			}
+			stdout = true
+		} else {
+			if files[file] {
+				return nil, fmt.Errorf(ErrOutputDuplicate, file)
+			}
+			files[file] = true
+		}
+		outputs = append(outputs, OutputSpec{format, file})
+	}
+	return outputs, nil
+}
+
+// OutputSpecs returns the outputs of the run: the `--output` values, or the Acharya records written to
+// OutputFile, stdout when it is empty
+func (o Options) OutputSpecs() ([]OutputSpec, error) {
+	if len(o.Outputs) == 0 {
+		return []OutputSpec{{FormatAcharya, o.OutputFile}}, nil
+	}
+	return ParseOutputs(o.Outputs)
+}
+
+// writesStdout reports whether any of the outputs is written to stdout
+func writesStdout(outputs []OutputSpec) bool {
+	for _, output := range outputs {
+		if output.File == "" || output.File == StdinPath {
+			return true
+		}
+	}
+	return false
+}
+
+// documentWriter renders the converted documents in a format, one at a time
+type documentWriter struct {
+	w                   io.Writer
+	format              string
+	tokenizer, splitter string
+	// source is the .txt of the previous document, the segments of a document share its CoNLL -DOCSTART-
+	source  string
+	started bool
+}
+
+func newDocumentWriter(w io.Writer, format, tokenizer, splitter string) (*documentWriter, error) {
+	if !containsString(outputFormats, format) {
+		return nil, fmt.Errorf(ErrOutputUnknownWrite, format)
+	}
+	return &documentWriter{w: w, format: format, tokenizer: tokenizer, splitter: splitter}, nil
+}
+
+// WriteDocument renders the document, record is its Acharya record
+func (d *documentWriter) WriteDocument(doc *Document, record string) error {
+	switch d.format {
+	case FormatCoNLL:
+		docStart := !d.started || doc.TxtPath != d.source || doc.Meta.SourceDoc == ""
+		d.source, d.started = doc.TxtPath, true
+		// A document is written with the tools of its own tools.conf
+		tokenizer, splitter := d.tokenizer, d.splitter
+		if doc.Tokenizer != "" {
+			tokenizer = doc.Tokenizer
+		}
+		if doc.Splitter != "" {
+			splitter = doc.Splitter
+		}
+		return writeCoNLLDocument(d.w, doc, docStart, tokenizer, splitter)
+	case FormatSpaCy:
+		return writeSpaCyRecord(d.w, doc)
+	}
+	_, err := io.WriteString(d.w, record)
+	return err
+}
+
+// reset forgets the previous document, the next one starts a new CoNLL document
+func (d *documentWriter) reset() {
+	d.source, d.started = "", false
+}
+
+// outputWriter receives the documents of an output while they are converted, nothing is left behind when it
+// is aborted
+type outputWriter interface {
+	WriteDocument(doc *Document, record string) error
+	Commit() error
+	Abort()
+}
+
+// streamWriter writes the documents of an output to a single file, or stdout
+type streamWriter struct {
+	stream *outputStream
+	render *documentWriter
+}
+
+func (s *streamWriter) WriteDocument(doc *Document, record string) error {
+	return s.render.WriteDocument(doc, record)
+}
+
+func (s *streamWriter) Commit() error {
+	return s.stream.Commit()
+}
+
+func (s *streamWriter) Abort() {
+	s.stream.Abort()
+}
+
+// outputWriters are the outputs of a run, every document is written to all of them as soon as it is converted
+type outputWriters []outputWriter
+
+// openOutputs opens every output of the run, in shards with `--shard-size` or `--shard-bytes`. No output is
+// opened when one of the files already exists without `--force`.
+func openOutputs(opts Options, outputs []OutputSpec, tokenizer, splitter string) (outputWriters, error) {
+	mode, err := opts.OutputMode()
+	if err != nil {
+		return nil, err
+	}
+	if opts.sharded() && writesStdout(outputs) {
+		return nil, errors.New(ErrValidateShardNoFile)
+	}
+	if !opts.OverWrite {
+		for _, output := range outputs {
+			files := []string{output.File}
+			if opts.sharded() {
+				files = []string{ShardOutputPath(output.File, 0), ManifestPath(output.File)}
+			}
+			for _, file := range files {
+				if file == "" || file == StdinPath {
+					continue
+				}
+				if _, err := os.Stat(file); !os.IsNotExist(err) {
+					return nil, fmt.Errorf("%s: %s", file, ErrFlagFileAlreadyExists)
+				}
+			}
+		}
+	}
+
+	writers := outputWriters{}
+	for _, output := range outputs {
+		var writer outputWriter
+		if opts.sharded() {
+			writer, err = newShardWriter(opts, output, mode, tokenizer, splitter)
+		} else {
+			writer, err = newStreamWriter(output, mode, tokenizer, splitter)
+		}
+		if err != nil {
+			writers.Abort()
+			return nil, err
+		}
+		writers = append(writers, writer)
+	}
+	return writers, nil
+}
+
+func newStreamWriter(output OutputSpec, mode OutputMode, tokenizer, splitter string) (*streamWriter, error) {
+	stream, err := openOutputStream(output.File, mode)
+	if err != nil {
+		return nil, err
+	}
+	render, err := newDocumentWriter(stream, output.Format, tokenizer, splitter)
+	if err != nil {
+		stream.Abort()
+		return nil, err
+	}
+	return &streamWriter{stream, render}, nil
+}
+
+// WriteDocument writes the document to every output
+func (o outputWriters) WriteDocument(doc *Document, record string) error {
+	for _, writer := range o {
+		if err := writer.WriteDocument(doc, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit completes every output, the outputs left are aborted when one of them fails
+func (o outputWriters) Commit() error {
+	for i, writer := range o {
+		if err := writer.Commit(); err != nil {
+			o[i+1:].Abort()
+			return err
+		}
+	}
+	return nil
+}
+
+func (o outputWriters) Abort() {
+	for _, writer := range o {
+		writer.Abort()
+	}
+}
+
+// writeOutputs writes the documents to every output of the run, records holds their Acharya records
+func writeOutputs(opts Options, outputs []OutputSpec, docs []*Document, records []string, tokenizer, splitter string) error {
+	writers, err := openOutputs(opts, outputs, tokenizer, splitter)
+	if err != nil {
+		return err
+	}
+	for i, doc := range docs {
+		if err = writers.WriteDocument(doc, records[i]); err != nil {
+			writers.Abort()
+			return err
+		}
+	}
+	return writers.Commit()
+}
+
+// BIOLabels returns the BIO label of every token, the first entity overlapping a token labels it
+func BIOLabels(tokens []Span, entities []NumberAcharyaEntity) []string {
+	labels := make([]string, len(tokens))
+	previous := -1
+	for i, token := range tokens {
+		labels[i] = outsideLabel
+		current := -1
+		for j, ent := range entities {
+			if ent.Entity.Begin < token.End && token.Begin < ent.Entity.End {
+				current = j
+				break
+			}
+		}
+		switch {
+		case current == -1:
+		case current == previous:
+			labels[i] = "I-" + entities[current].Entity.Name
+		default:
+			labels[i] = "B-" + entities[current].Entity.Name
+		}
+		previous = current
+	}
+	return labels
+}
+
+// CoNLL writes the documents as tab separated `token label` lines with BIO labels, one blank line after every
+// sentence. Every source document starts with a `-DOCSTART-` line, the segments of a document share it.
+func CoNLL(w io.Writer, docs []*Document, tokenizer, splitter string) error {
+	render, err := newDocumentWriter(w, FormatCoNLL, tokenizer, splitter)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err = render.WriteDocument(doc, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCoNLLDocument writes a document in CoNLL, after a `-DOCSTART-` line with docStart
+func writeCoNLLDocument(w io.Writer, doc *Document, docStart bool, tokenizer, splitter string) error {
+	// Every document is written on its own, only one document is held in memory
+	b := &strings.Builder{}
+	if docStart {
+		b.WriteString(conllDocStart + "\n\n")
+	}
+
+	chars := textChars(doc.Data)
+	tokens := Tokenize(doc.Data, tokenizer)
+	labels := BIOLabels(tokens, doc.Entities)
+	sentences := SegmentText(doc.Data, SegmentSentence, splitter)
+
+	sentence := 0
+	written := false
+	for t, token := range tokens {
+		for sentence < len(sentences)-1 && token.Begin >= sentences[sentence].End {
+			sentence++
+			if written {
+				b.WriteString("\n")
+				written = false
+			}
+		}
+		fmt.Fprintf(b, "%s\t%s\n", string(chars[token.Begin:token.End]), labels[t])
+		written = true
+	}
+	if written {
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// spaCyRecord is the `{"text": ..., "entities": [[start, end, label]]}` training data of spaCy
+type spaCyRecord struct {
+	Text     string          `json:"text"`
+	Entities [][]interface{} `json:"entities"`
+}
+
+// SpaCy writes the documents as spaCy training data, one JSON record per line. The text is written without `\r`
+// so the offsets are the character offsets of the text, as spaCy counts them.
+func SpaCy(w io.Writer, docs []*Document) error {
+	for _, doc := range docs {
+		if err := writeSpaCyRecord(w, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSpaCyRecord writes the spaCy record of a document
+func writeSpaCyRecord(w io.Writer, doc *Document) error {
+	record := spaCyRecord{Text: string(textChars(doc.Data)), Entities: [][]interface{}{}}
+	entities := append([]NumberAcharyaEntity{}, doc.Entities...)
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].Entity.Begin < entities[j].Entity.Begin
+	})
+	for _, ent := range entities {
+		record.Entities = append(record.Entities, []interface{}{ent.Entity.Begin, ent.Entity.End, ent.Entity.Name})
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OutputsSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *OutputsSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "outputs")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *OutputsSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *OutputsSuite) TestParseOutputs() {
+	outputs, err := ParseOutputs([]string{"out.jsonl", "conll=out.conll", "spacy=-", "acharya=a=b.jsonl", "x=y.jsonl"})
+	suite.Nil(err)
+	suite.Equal([]OutputSpec{
+		{FormatAcharya, "out.jsonl"}, {FormatCoNLL, "out.conll"}, {FormatSpaCy, "-"}, {FormatAcharya, "a=b.jsonl"}, {FormatAcharya, "x=y.jsonl"},
+	}, outputs)
+
+	_, err = ParseOutputs([]string{"out.jsonl", "acharya=out.jsonl"})
+	suite.EqualError(err, "out.jsonl is written by more than one `--output`")
+	_, err = ParseOutputs([]string{"-", "conll="})
+	suite.EqualError(err, ErrOutputStdoutTwice)
+
+	outputs, err = Options{OutputFile: "out.jsonl"}.OutputSpecs()
+	suite.Nil(err)
+	suite.Equal([]OutputSpec{{FormatAcharya, "out.jsonl"}}, outputs)
+}
+
+func (suite *OutputsSuite) TestBIOLabels() {
+	tokens := []Span{{0, 4}, {5, 10}, {11, 13}, {14, 19}, {20, 25}}
+	entities := []NumberAcharyaEntity{{1, AcharyaEntity{0, 10, "Person"}}, {2, AcharyaEntity{14, 19, "GPE"}}, {3, AcharyaEntity{20, 25, "GPE"}}}
+	suite.Equal([]string{"B-Person", "I-Person", "O", "B-GPE", "B-GPE"}, BIOLabels(tokens, entities))
+}
+
+func (suite *OutputsSuite) conll(docs []*Document, tokenizer, splitter string) string {
+	b := &bytes.Buffer{}
+	suite.Nil(CoNLL(b, docs, tokenizer, splitter))
+	return b.String()
+}
+
+func (suite *OutputsSuite) TestCoNLL() {
+	docs := []*Document{
+		{TxtPath: "a.txt", Data: "John Smith went to Paris. He left.", Entities: []NumberAcharyaEntity{{1, AcharyaEntity{0, 10, "Person"}}, {2, AcharyaEntity{19, 24, "GPE"}}}},
+		{TxtPath: "b.txt", Data: "Sony\r\nrocks", Entities: []NumberAcharyaEntity{{1, AcharyaEntity{0, 4, "Organization"}}}},
+	}
+	suite.Equal("-DOCSTART-\tO\n\nJohn\tB-Person\nSmith\tI-Person\nwent\tO\nto\tO\nParis.\tB-GPE\nHe\tO\nleft.\tO\n\n"+
+		"-DOCSTART-\tO\n\nSony\tB-Organization\n\nrocks\tO\n\n", suite.conll(docs, TokenizerWhitespace, SplitterNewline))
+	suite.Equal("-DOCSTART-\tO\n\nJohn\tB-Person\nSmith\tI-Person\nwent\tO\nto\tO\nParis\tB-GPE\n.\tO\nHe\tO\nleft\tO\n.\tO\n\n",
+		suite.conll(docs[:1], TokenizerPTBLike, SplitterNewline))
+
+	// The segments of a document share its -DOCSTART-
+	offset := 26
+	segments := []*Document{
+		{TxtPath: "a.txt", Data: "John Smith went to Paris.", Meta: RecordMeta{SourceDoc: "a.txt"}},
+		{TxtPath: "a.txt", Data: "He left.", Meta: RecordMeta{SourceDoc: "a.txt", SourceOffset: &offset}},
+	}
+	suite.Equal("-DOCSTART-\tO\n\nJohn\tO\nSmith\tO\nwent\tO\nto\tO\nParis.\tO\n\nHe\tO\nleft.\tO\n\n", suite.conll(segments, TokenizerWhitespace, SplitterRegex))
+}
+
+func (suite *OutputsSuite) TestSpaCy() {
+	docs := []*Document{{Data: "Öl\r\nin Sony", Entities: []NumberAcharyaEntity{{2, AcharyaEntity{6, 10, "Organization"}}, {1, AcharyaEntity{0, 2, "Product"}}}}}
+	spacy := &bytes.Buffer{}
+	suite.Nil(SpaCy(spacy, docs))
+	suite.Equal("{\"text\":\"Öl\\nin Sony\",\"entities\":[[0,2,\"Product\"],[6,10,\"Organization\"]]}\n", spacy.String())
+}
+
+func (suite *OutputsSuite) TestMultipleOutputs() {
+	acharya := filepath.Join(suite.TmpDir, "out.jsonl")
+	conll := filepath.Join(suite.TmpDir, "out.conll")
+	spacy := filepath.Join(suite.TmpDir, "out.spacy.jsonl")
+	opts := Options{FolderPath: "./testData/news", Include: []string{"000-*"}, Outputs: []string{acharya, "conll=" + conll, "spacy=" + spacy}}
+	suite.Nil(ValidateFlags(opts))
+	suite.Nil(handleMain(opts))
+
+	expected := filepath.Join(suite.TmpDir, "expected.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: "./testData/news", Include: []string{"000-*"}, OutputFile: expected}))
+	for _, output := range []string{conll, spacy} {
+		info, err := os.Stat(output)
+		suite.Nil(err)
+		suite.NotZero(info.Size())
+	}
+	converted, err := ioutil.ReadFile(acharya)
+	suite.Nil(err)
+	original, err := ioutil.ReadFile(expected)
+	suite.Nil(err)
+	suite.Equal(string(original), string(converted))
+
+	// No output is written when one of them exists
+	suite.Nil(os.Remove(spacy))
+	suite.NotNil(handleMain(opts))
+	_, err = os.Stat(spacy)
+	suite.True(os.IsNotExist(err))
+
+	opts.OverWrite = true
+	suite.Nil(handleMain(opts))
+}
+
+func (suite *OutputsSuite) TestSplitOutputs() {
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	conll := filepath.Join(suite.TmpDir, "out.conll")
+	opts := Options{FolderPath: "./testData/news", Split: "train=0.5,test=0.5", Outputs: []string{output, "conll=" + conll}}
+	suite.Nil(ValidateFlags(opts))
+	suite.Nil(handleMain(opts))
+	for _, name := range []string{"out.train.jsonl", "out.test.jsonl", "out.train.conll", "out.test.conll"} {
+		_, err := os.Stat(filepath.Join(suite.TmpDir, name))
+		suite.Nil(err, name)
+	}
+
+	opts.Outputs = []string{output, "conll=-"}
+	suite.EqualError(ValidateFlags(opts), ErrValidateSplitNoOp)
+}
+
+func (suite *OutputsSuite) TestStdoutHoldsOnlyRecords() {
+	r, w, err := os.Pipe()
+	suite.Nil(err)
+	// The records are read while they are written, the pipe would block once full
+	read := make(chan []byte)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		read <- data
+	}()
+	stdout := os.Stdout
+	os.Stdout = w
+	err = handleMain(Options{FolderPath: "./testData/news", Outputs: []string{"conll=" + filepath.Join(suite.TmpDir, "out.conll"), "-"}, Tokenizer: TokenizerWhitespace})
+	os.Stdout = stdout
+	suite.Nil(w.Close())
+	suite.Nil(err)
+
+	data := <-read
+	suite.NotEmpty(data)
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		suite.True(bytes.HasPrefix(line, []byte("{\"Data\":")), string(line))
+	}
+}
+
+func (suite *OutputsSuite) TestFailedRunLeavesNoOutput() {
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	conll := filepath.Join(suite.TmpDir, "out.conll")
+	opts := Options{
+		AnnFiles: "./testData/news/000-introduction.ann,./testData/invalid-files/invalid-ann-tab/030-login.ann",
+		TxtFiles: "./testData/news/000-introduction.txt,./testData/news/030-login.txt",
+		ConfFile: "./testData/news/annotation.conf",
+		Outputs:  []string{output, "conll=" + conll},
+	}
+	suite.NotNil(handleMain(opts))
+	files, err := ioutil.ReadDir(suite.TmpDir)
+	suite.Nil(err)
+	suite.Empty(files)
+
+	// The segments of a document share a -DOCSTART- in CoNLL, the first one of a shard starts its own
+	opts = Options{FolderPath: "./testData/news", Include: []string{"000-*"}, Outputs: []string{"conll=" + conll}, Segment: SegmentSentence, Crossing: CrossingDrop, ShardSize: 2, Tokenizer: TokenizerWhitespace, Splitter: SplitterRegex}
+	suite.Nil(handleMain(opts))
+	for _, shard := range []string{"out-00000.conll", "out-00001.conll"} {
+		data, err := ioutil.ReadFile(filepath.Join(suite.TmpDir, shard))
+		suite.Nil(err)
+		suite.Equal(1, strings.Count(string(data), conllDocStart), shard)
+		suite.True(strings.HasPrefix(string(data), conllDocStart), shard)
+	}
+}
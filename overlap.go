@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	OverlapKeepLongest  = "keep-longest"
+	OverlapKeepShortest = "keep-shortest"
+	OverlapKeepFirst    = "keep-first"
+	OverlapPriority     = "priority"
+	OverlapFail         = "fail"
+
+	ErrOverlapUnknownStrategy = "unknown overlap strategy: %s, expected `keep-longest`, `keep-shortest`, `keep-first`, `priority` or `fail`"
+	ErrOverlappingEntities    = "overlapping entities"
+	InfoOverlapResolved       = "%s: %s overlaps %s, kept %s"
+)
+
+var ErrParseOverlap = errors.New(ErrOverlappingEntities)
+
+var overlapStrategies = []string{OverlapKeepLongest, OverlapKeepShortest, OverlapKeepFirst, OverlapPriority, OverlapFail}
+
+// ConflictEntity is an entity of an overlap conflict, as reported in the error report
+type ConflictEntity struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Begin int    `json:"begin"`
+	End   int    `json:"end"`
+}
+
+func newConflictEntity(ent NumberAcharyaEntity) ConflictEntity {
+	return ConflictEntity{fmt.Sprintf("T%d", ent.TxtAnnNo), ent.Entity.Name, ent.Entity.Begin, ent.Entity.End}
+}
+
+func (c ConflictEntity) String() string {
+	return fmt.Sprintf("%s %s %d %d", c.ID, c.Type, c.Begin, c.End)
+}
+
+// OverlapConflict is a pair of overlapping entities of which only one was kept
+type OverlapConflict struct {
+	File     string         `json:"file"`
+	Strategy string         `json:"strategy"`
+	Kept     ConflictEntity `json:"kept"`
+	Dropped  ConflictEntity `json:"dropped"`
+}
+
+func (c OverlapConflict) String() string {
+	return fmt.Sprintf(InfoOverlapResolved, c.File, c.Dropped, c.Kept, c.Kept.ID)
+}
+
+func ValidateOverlapStrategy(strategy string) error {
+	for _, known := range overlapStrategies {
+		if strategy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf(ErrOverlapUnknownStrategy, strategy)
+}
+
+func entitiesOverlap(a, b AcharyaEntity) bool {
+	return a.Begin < b.End && b.Begin < a.End
+}
+
+// ResolveOverlaps removes the overlapping and nested entities so that no two entities share a character.
+// The entities are ranked following strategy and an entity is only kept when it does not overlap a
+// better ranked one. typeOrder is the order of the types in `[entities]`, used by the priority strategy.
+// The fail strategy returns a *ParseError for the first conflict instead.
+func ResolveOverlaps(entities []NumberAcharyaEntity, strategy string, typeOrder []string) ([]NumberAcharyaEntity, []OverlapConflict, error) {
+	if err := ValidateOverlapStrategy(strategy); err != nil {
+		return nil, nil, err
+	}
+
+	rank := make(map[string]int)
+	for i, name := range typeOrder {
+		rank[name] = i
+	}
+	typeRank := func(name string) int {
+		if r, ok := rank[name]; ok {
+			return r
+		}
+		return len(typeOrder)
+	}
+
+	order := make([]int, len(entities))
+	for i := range order {
+		order[i] = i
+	}
+	length := func(i int) int {
+		return entities[i].Entity.End - entities[i].Entity.Begin
+	}
+	sort.SliceStable(order, func(x, y int) bool {
+		a, b := order[x], order[y]
+		switch strategy {
+		case OverlapKeepShortest:
+			if length(a) != length(b) {
+				return length(a) < length(b)
+			}
+		case OverlapKeepFirst:
+			if entities[a].Entity.Begin != entities[b].Entity.Begin {
+				return entities[a].Entity.Begin < entities[b].Entity.Begin
+			}
+		case OverlapPriority:
+			if typeRank(entities[a].Entity.Name) != typeRank(entities[b].Entity.Name) {
+				return typeRank(entities[a].Entity.Name) < typeRank(entities[b].Entity.Name)
+			}
+		}
+		// Ties are broken by keeping the longest, then the first entity
+		if length(a) != length(b) {
+			return length(a) > length(b)
+		}
+		return entities[a].Entity.Begin < entities[b].Entity.Begin
+	})
+
+	kept := make([]bool, len(entities))
+	keptOrder := []int{}
+	conflicts := []OverlapConflict{}
+	for _, candidate := range order {
+		overlapping := -1
+		for _, k := range keptOrder {
+			if entitiesOverlap(entities[k].Entity, entities[candidate].Entity) {
+				overlapping = k
+				break
+			}
+		}
+		if overlapping == -1 {
+			kept[candidate] = true
+			keptOrder = append(keptOrder, candidate)
+			continue
+		}
+
+		if strategy == OverlapFail {
+			first, second := entities[overlapping], entities[candidate]
+			if second.Entity.Begin < first.Entity.Begin {
+				first, second = second, first
+			}
+			return nil, nil, &ParseError{ID: newConflictEntity(second).ID, Kind: ErrParseOverlap,
+				Err: fmt.Errorf("%s overlaps %s", newConflictEntity(second), newConflictEntity(first))}
+		}
+		conflicts = append(conflicts, OverlapConflict{
+			Strategy: strategy,
+			Kept:     newConflictEntity(entities[overlapping]),
+			Dropped:  newConflictEntity(entities[candidate]),
+		})
+	}
+
+	resolved := []NumberAcharyaEntity{}
+	for i, ent := range entities {
+		if kept[i] {
+			resolved = append(resolved, ent)
+		}
+	}
+	return resolved, conflicts, nil
+}
+
+// GetEntityTypesFromFile returns the types of the `[entities]` section in the order they are defined
+func GetEntityTypesFromFile(confFile io.Reader) []string {
+	scanner := bufio.NewScanner(confFile)
+	scanner.Split(bufio.ScanLines)
+	startScan := false
+	types := []string{}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.Contains(line, "[entities]") {
+			startScan = true
+			continue
+		}
+		if !startScan || line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+		types = append(types, strings.TrimPrefix(line, "!"))
+	}
+	return types
+}
+
+// EntityOrder returns the types of the `[entities]` of the conf at confPath, in the order they are defined
+func (c *Collection) EntityOrder(confPath string) ([]string, error) {
+	if order, ok := c.confOrders[confPath]; ok {
+		return order, nil
+	}
+
+	confFile, err := c.Open(confPath)
+	if err != nil {
+		return nil, err
+	}
+	defer confFile.Close()
+
+	c.confOrders[confPath] = GetEntityTypesFromFile(confFile)
+	return c.confOrders[confPath], nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OverlapSuite struct {
+	suite.Suite
+}
+
+// "Bank of New York City"
+var overlapEntities = []NumberAcharyaEntity{
+	{1, AcharyaEntity{0, 21, "Organization"}},
+	{2, AcharyaEntity{8, 16, "GPE"}},
+	{3, AcharyaEntity{8, 21, "GPE"}},
+	{4, AcharyaEntity{25, 30, "Person"}},
+}
+
+func (suite *OverlapSuite) ids(entities []NumberAcharyaEntity) []int {
+	ids := []int{}
+	for _, ent := range entities {
+		ids = append(ids, ent.TxtAnnNo)
+	}
+	return ids
+}
+
+func (suite *OverlapSuite) TestResolveOverlaps() {
+	resolved, conflicts, err := ResolveOverlaps(overlapEntities, OverlapKeepLongest, nil)
+	suite.Nil(err)
+	suite.Equal([]int{1, 4}, suite.ids(resolved))
+	suite.Equal([]OverlapConflict{
+		{Strategy: OverlapKeepLongest, Kept: ConflictEntity{"T1", "Organization", 0, 21}, Dropped: ConflictEntity{"T3", "GPE", 8, 21}},
+		{Strategy: OverlapKeepLongest, Kept: ConflictEntity{"T1", "Organization", 0, 21}, Dropped: ConflictEntity{"T2", "GPE", 8, 16}},
+	}, conflicts)
+
+	resolved, _, err = ResolveOverlaps(overlapEntities, OverlapKeepShortest, nil)
+	suite.Nil(err)
+	suite.Equal([]int{2, 4}, suite.ids(resolved))
+
+	resolved, _, err = ResolveOverlaps(overlapEntities, OverlapKeepFirst, nil)
+	suite.Nil(err)
+	suite.Equal([]int{1, 4}, suite.ids(resolved))
+
+	resolved, _, err = ResolveOverlaps(overlapEntities, OverlapPriority, []string{"Person", "GPE", "Organization"})
+	suite.Nil(err)
+	suite.Equal([]int{3, 4}, suite.ids(resolved))
+
+	_, _, err = ResolveOverlaps(overlapEntities, OverlapFail, nil)
+	suite.True(errors.Is(err, ErrParseOverlap))
+	suite.EqualError(err, "T3: overlapping entities: T3 GPE 8 21 overlaps T1 Organization 0 21")
+
+	_, _, err = ResolveOverlaps(overlapEntities, "keep-all", nil)
+	suite.EqualError(err, "unknown overlap strategy: keep-all, expected `keep-longest`, `keep-shortest`, `keep-first`, `priority` or `fail`")
+
+	resolved, conflicts, err = ResolveOverlaps(overlapEntities[3:], OverlapFail, nil)
+	suite.Nil(err)
+	suite.Equal([]int{4}, suite.ids(resolved))
+	suite.Empty(conflicts)
+}
+
+func (suite *OverlapSuite) TestGetEntityTypesFromFile() {
+	conf := "[entities]\nPerson\n!Location\n\tGPE\n# comment\nMoney\n[relations]\nFamily\n"
+	suite.Equal([]string{"Person", "Location", "GPE", "Money"}, GetEntityTypesFromFile(strings.NewReader(conf)))
+}
+
+func (suite *OverlapSuite) TestHandleMainOverlap() {
+	dir, err := ioutil.TempDir("", "overlap")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "annotation.conf"), []byte("[entities]\nGPE\nOrganization\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "bank.txt"), []byte("Bank of New York City"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "bank.ann"), []byte("T1\tOrganization 0 21\tBank of New York City\nT2\tGPE 8 21\tNew York City\n"), 0600))
+
+	output := filepath.Join(dir, "out.jsonl")
+	report := filepath.Join(dir, "report.json")
+	err = handleMain(Options{FolderPath: dir, OutputFile: output, Overlap: OverlapPriority, ErrorReport: report})
+	suite.Nil(err)
+
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Contains(string(data), `"Entities":[[8,21,"GPE"]]`)
+
+	data, err = ioutil.ReadFile(report)
+	suite.Nil(err)
+	errorReport := ErrorReport{}
+	suite.Nil(json.Unmarshal(data, &errorReport))
+	suite.Len(errorReport.Conflicts, 1)
+	suite.Equal("T1", errorReport.Conflicts[0].Dropped.ID)
+
+	err = handleMain(Options{FolderPath: dir, Overlap: OverlapFail, KeepGoing: true, ErrorReport: report, OutputFile: output, OverWrite: true})
+	suite.EqualError(err, "1 of 1 documents failed to convert")
+	data, err = ioutil.ReadFile(report)
+	suite.Nil(err)
+	suite.Contains(string(data), `"code": "overlapping_entities"`)
+}
+
+func (suite *OverlapSuite) TestLabelMapBeforeOverlap() {
+	dir, err := ioutil.TempDir("", "overlap")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "annotation.conf"), []byte("[entities]\nOrganization\nPerson\nGPE\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "ford.txt"), []byte("Henry Ford Company"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "ford.ann"), []byte("T1\tOrganization 0 18\tHenry Ford Company\nT2\tPerson 0 10\tHenry Ford\n"), 0600))
+	labelMap := filepath.Join(dir, "map.json")
+	suite.Nil(ioutil.WriteFile(labelMap, []byte(`{"drop": ["Organization"]}`), 0600))
+
+	// The dropped Organization does not hide the Person it contains
+	output := filepath.Join(dir, "out.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: dir, OutputFile: output, Overlap: OverlapKeepLongest, LabelMap: labelMap}))
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Contains(string(data), `"Entities":[[0,10,"Person"]]`)
+
+	// The priority follows the conf order of the mapped types
+	suite.Nil(ioutil.WriteFile(labelMap, []byte(`{"rename": {"GPE": "Place", "Organization": "Place"}}`), 0600))
+	suite.Nil(handleMain(Options{FolderPath: dir, OutputFile: output, OverWrite: true, Overlap: OverlapPriority, LabelMap: labelMap}))
+	data, err = ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Contains(string(data), `"Entities":[[0,18,"Place"]]`)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	ErrProjectUnknownSetting = "%s: unknown setting `%s`, settings are named after the command line flags"
+	ErrProjectBadValue       = "%s: setting `%s`: %v"
+	ErrProjectNotAList       = "%s: setting `%s` takes a single value"
+	ErrProjectNestedValue    = "%s: setting `%s` should be a value or a list of values"
+)
+
+// projectFileNames are looked for, in this order, in the root of the collection
+var projectFileNames = []string{"bratconv.yaml", "bratconv.yml", "bratconv.toml"}
+
+// projectPathSettings hold paths, they are relative to the project file rather than to the working directory
+var projectPathSettings = map[string]bool{
+	"folderPath":   true,
+	"ann":          true,
+	"txt":          true,
+	"conf":         true,
+	"files-from":   true,
+	"output":       true,
+	"error-report": true,
+	"label-map":    true,
+	"schema":       true,
+	"cache-dir":    true,
+}
+
+// projectPatternSettings hold globs, they are resolved like the path settings once the folder of the run is known
+var projectPatternSettings = map[string]bool{"include": true, "exclude": true}
+
+// projectIgnoredSettings can only be given on the command line
+var projectIgnoredSettings = map[string]bool{"config": true, "version": true, "help": true}
+
+// FindProjectFile returns the project file in the root of the collection folder, or "" when there is none
+func FindProjectFile(folderPath string) string {
+	if folderPath == "" || folderPath == StdinPath || IsArchive(folderPath) {
+		return ""
+	}
+	for _, name := range projectFileNames {
+		projectFile := filepath.Join(folderPath, name)
+		if info, err := os.Stat(projectFile); err == nil && !info.IsDir() {
+			return projectFile
+		}
+	}
+	return ""
+}
+
+// LoadProjectFile reads the settings of a project file, TOML for .toml files and YAML otherwise
+func LoadProjectFile(projectFile string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(projectFile)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]interface{})
+	if strings.ToLower(filepath.Ext(projectFile)) == ".toml" {
+		_, err = toml.Decode(string(data), &settings)
+	} else {
+		err = yaml.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", projectFile, err)
+	}
+	return settings, nil
+}
+
+// ApplyProjectFile sets the flags of the settings of the project file. A setting is named after its flag, with
+// `-` or `_`, and is ignored when the flag was given on the command line, so the flags always win.
+func ApplyProjectFile(flags *flag.FlagSet, projectFile string) error {
+	settings, err := LoadProjectFile(projectFile)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// patterns are set last, they are relative to the folder of the run when there is one
+	patterns := make(map[string][]string)
+	for _, name := range names {
+		flagName := strings.Replace(name, "_", "-", -1)
+		f := flags.Lookup(flagName)
+		if f == nil || projectIgnoredSettings[flagName] {
+			return fmt.Errorf(ErrProjectUnknownSetting, projectFile, name)
+		}
+		if f.Changed {
+			continue
+		}
+
+		values, err := projectValues(projectFile, name, settings[name])
+		if err != nil {
+			return err
+		}
+		if len(values) > 1 && !strings.HasSuffix(f.Value.Type(), "Array") && !strings.HasSuffix(f.Value.Type(), "Slice") {
+			return fmt.Errorf(ErrProjectNotAList, projectFile, name)
+		}
+		if projectPatternSettings[flagName] {
+			patterns[flagName] = append(patterns[flagName], values...)
+			continue
+		}
+		for _, value := range values {
+			if flagName == "output" {
+				// The format of an output is kept in front of its path
+				if format, file := splitOutputFormat(value); file != value {
+					value = format + "=" + projectPath(projectFile, file)
+				} else {
+					value = projectPath(projectFile, value)
+				}
+			} else if projectPathSettings[flagName] {
+				value = projectPath(projectFile, value)
+			}
+			if err := flags.Set(flagName, value); err != nil {
+				return fmt.Errorf(ErrProjectBadValue, projectFile, name, err)
+			}
+		}
+	}
+
+	folderPath := ""
+	if f := flags.Lookup("folderPath"); f != nil {
+		folderPath = f.Value.String()
+	}
+	for _, flagName := range []string{"include", "exclude"} {
+		for _, pattern := range patterns[flagName] {
+			if err := flags.Set(flagName, projectPattern(projectFile, folderPath, pattern)); err != nil {
+				return fmt.Errorf(ErrProjectBadValue, projectFile, flagName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// projectPattern resolves a glob relative to the directory of the project file. The globs of a folder match the
+// paths relative to the folder, the pattern is made relative to it.
+func projectPattern(projectFile, folderPath, pattern string) string {
+	if filepath.IsAbs(pattern) {
+		return pattern
+	}
+	resolved := filepath.Join(filepath.Dir(projectFile), pattern)
+	switch {
+	case folderPath == StdinPath || IsArchive(folderPath):
+		// The entries of an archive are matched as they are named in it
+		return pattern
+	case folderPath == "":
+		return resolved
+	}
+	if rel, err := filepath.Rel(folderPath, resolved); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return resolved
+}
+
+// projectValues returns the value of a setting as flag values, a list gives one value per item
+func projectValues(projectFile, name string, setting interface{}) ([]string, error) {
+	items, ok := setting.([]interface{})
+	if !ok {
+		items = []interface{}{setting}
+	}
+
+	values := []string{}
+	for _, item := range items {
+		switch item.(type) {
+		case []interface{}, map[interface{}]interface{}, map[string]interface{}:
+			return nil, fmt.Errorf(ErrProjectNestedValue, projectFile, name)
+		}
+		values = append(values, fmt.Sprint(item))
+	}
+	return values, nil
+}
+
+// projectPath resolves the comma separated paths of a setting relative to the directory of the project file
+func projectPath(projectFile, value string) string {
+	paths := strings.Split(value, ",")
+	for i, p := range paths {
+		p = strings.TrimSpace(p)
+		if p != "" && p != StdinPath && !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(projectFile), p)
+		}
+		paths[i] = p
+	}
+	return strings.Join(paths, ",")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	flag "github.com/spf13/pflag"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProjectSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *ProjectSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "project")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *ProjectSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *ProjectSuite) writeProject(name, content string) string {
+	projectFile := filepath.Join(suite.TmpDir, name)
+	suite.Nil(ioutil.WriteFile(projectFile, []byte(content), 0600))
+	return projectFile
+}
+
+func newProjectFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.StringP("output", "o", "", "")
+	flags.StringP("folderPath", "p", "", "")
+	flags.StringArrayP("include", "i", []string{}, "")
+	flags.StringArrayP("exclude", "x", []string{}, "")
+	flags.BoolP("keep-going", "k", false, "")
+	flags.Int("window", 0, "")
+	flags.String("label-map", "", "")
+	flags.String("overlap", "", "")
+	flags.String("config", "", "")
+	return flags
+}
+
+func (suite *ProjectSuite) TestApplyYAML() {
+	projectFile := suite.writeProject("bratconv.yaml", "output: out/news.jsonl\ninclude: ['**/0*.ann', '**/1*.ann']\n"+
+		"keep_going: true\nwindow: 256\nlabel-map: /etc/labels.yaml\noverlap: keep-longest\n")
+
+	flags := newProjectFlags()
+	suite.Nil(flags.Parse([]string{"--overlap", "fail"}))
+	suite.Nil(ApplyProjectFile(flags, projectFile))
+
+	output, _ := flags.GetString("output")
+	suite.Equal(filepath.Join(suite.TmpDir, "out/news.jsonl"), output)
+	include, _ := flags.GetStringArray("include")
+	suite.Equal([]string{filepath.Join(suite.TmpDir, "**/0*.ann"), filepath.Join(suite.TmpDir, "**/1*.ann")}, include)
+	keepGoing, _ := flags.GetBool("keep-going")
+	suite.True(keepGoing)
+	window, _ := flags.GetInt("window")
+	suite.Equal(256, window)
+	labelMap, _ := flags.GetString("label-map")
+	suite.Equal("/etc/labels.yaml", labelMap)
+	// The command line wins over the project file
+	overlap, _ := flags.GetString("overlap")
+	suite.Equal("fail", overlap)
+}
+
+func (suite *ProjectSuite) TestApplyTOML() {
+	projectFile := suite.writeProject("bratconv.toml", "output = \"-\"\ninclude = [\"a/*.ann\"]\nwindow = 128\n")
+
+	flags := newProjectFlags()
+	suite.Nil(ApplyProjectFile(flags, projectFile))
+	output, _ := flags.GetString("output")
+	suite.Equal("-", output)
+	include, _ := flags.GetStringArray("include")
+	suite.Equal([]string{filepath.Join(suite.TmpDir, "a/*.ann")}, include)
+	window, _ := flags.GetInt("window")
+	suite.Equal(128, window)
+}
+
+func (suite *ProjectSuite) TestApplyPatterns() {
+	// Without a folder, the globs are relative to the project file
+	suite.Nil(os.Mkdir(filepath.Join(suite.TmpDir, "conf"), 0700))
+	projectFile := suite.writeProject(filepath.Join("conf", "bratconv.yaml"), "include: [news/*.ann]\nexclude: ['news/0*.ann']\n")
+	flags := newProjectFlags()
+	suite.Nil(ApplyProjectFile(flags, projectFile))
+	include, _ := flags.GetStringArray("include")
+	suite.Equal([]string{filepath.Join(suite.TmpDir, "conf", "news/*.ann")}, include)
+
+	// The globs of a folder are matched relative to it
+	flags = newProjectFlags()
+	suite.Nil(flags.Parse([]string{"--folderPath", suite.TmpDir}))
+	suite.Nil(ApplyProjectFile(flags, projectFile))
+	exclude, _ := flags.GetStringArray("exclude")
+	suite.Equal([]string{"conf/news/0*.ann"}, exclude)
+
+	// A project file in the collection root selects its documents
+	projectFile = filepath.Join(suite.TmpDir, "bratconv.yaml")
+	suite.Nil(ioutil.WriteFile(projectFile, []byte("include: ['0[0-2]*']\nexclude: ['010-*']\n"), 0600))
+	for _, name := range []string{"000-introduction", "010-navigation", "020-top_bar", "030-login"} {
+		for _, ext := range []string{dotAnnSuffix, dotTxtSuffix} {
+			data, err := ioutil.ReadFile(filepath.Join("./testData/news", name+ext))
+			suite.Nil(err)
+			suite.Nil(ioutil.WriteFile(filepath.Join(suite.TmpDir, name+ext), data, 0600))
+		}
+	}
+	flags = newProjectFlags()
+	suite.Nil(flags.Parse([]string{"--folderPath", suite.TmpDir}))
+	suite.Nil(ApplyProjectFile(flags, projectFile))
+	include, _ = flags.GetStringArray("include")
+	exclude, _ = flags.GetStringArray("exclude")
+	collection, err := OpenCollection(Options{FolderPath: suite.TmpDir, Include: include, Exclude: exclude})
+	suite.Nil(err)
+	suite.Equal([]string{filepath.Join(suite.TmpDir, "000-introduction.ann"), filepath.Join(suite.TmpDir, "020-top_bar.ann")}, collection.Ann)
+}
+
+func (suite *ProjectSuite) TestApplyInvalid() {
+	projectFile := suite.writeProject("unknown.yaml", "outptu: out.jsonl\n")
+	suite.EqualError(ApplyProjectFile(newProjectFlags(), projectFile), projectFile+": unknown setting `outptu`, settings are named after the command line flags")
+
+	projectFile = suite.writeProject("config.yaml", "config: other.yaml\n")
+	suite.NotNil(ApplyProjectFile(newProjectFlags(), projectFile))
+
+	projectFile = suite.writeProject("list.yaml", "output: [a.jsonl, b.jsonl]\n")
+	suite.EqualError(ApplyProjectFile(newProjectFlags(), projectFile), projectFile+": setting `output` takes a single value")
+
+	projectFile = suite.writeProject("nested.yaml", "output:\n  file: a.jsonl\n")
+	suite.EqualError(ApplyProjectFile(newProjectFlags(), projectFile), projectFile+": setting `output` should be a value or a list of values")
+
+	projectFile = suite.writeProject("bad.yaml", "window: many\n")
+	suite.NotNil(ApplyProjectFile(newProjectFlags(), projectFile))
+}
+
+func (suite *ProjectSuite) TestFindProjectFile() {
+	suite.Equal("", FindProjectFile(suite.TmpDir))
+	projectFile := suite.writeProject("bratconv.toml", "")
+	suite.Equal(projectFile, FindProjectFile(suite.TmpDir))
+	projectFile = suite.writeProject("bratconv.yaml", "")
+	suite.Equal(projectFile, FindProjectFile(suite.TmpDir))
+
+	suite.Equal("", FindProjectFile(""))
+	suite.Equal("", FindProjectFile(StdinPath))
+	suite.Equal("", FindProjectFile("collection.zip"))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Machine readable error codes used in the error report
+const (
+	CodeDiscontinuous     = "discontinuous_textbound"
+	CodeBadFormat         = "bad_format"
+	CodeBadFormatTab      = "bad_format_tab"
+	CodeBadOffset         = "bad_offset"
+	CodeBadAnnotationID   = "bad_annotation_id"
+	CodeBadSpan           = "bad_span"
+	CodeUnknownAnnotation = "unknown_annotation"
+	CodeOverlap           = "overlapping_entities"
+	CodeNoEntities        = "no_entities"
+	CodeBadSection        = "bad_section"
+	CodeTextMismatch      = "text_mismatch"
+	CodeIO                = "io_error"
+	CodeUnpaired          = "unpaired_file"
+	CodeCrossing          = "crossing_entity"
+	CodeZeroWidth         = "zero_width_entity"
+	CodeUnknown           = "unknown"
+)
+
+// errorCodes maps the parse error categories to their code in the error report
+var errorCodes = map[error]string{
+	ErrParseDiscontinuous:     CodeDiscontinuous,
+	ErrParseBadFormat:         CodeBadFormat,
+	ErrParseBadFormatTab:      CodeBadFormatTab,
+	ErrParseBadOffset:         CodeBadOffset,
+	ErrParseBadAnnotationID:   CodeBadAnnotationID,
+	ErrParseBadSpan:           CodeBadSpan,
+	ErrParseUnknownAnnotation: CodeUnknownAnnotation,
+	ErrParseOverlap:           CodeOverlap,
+	ErrParseNoEntities:        CodeNoEntities,
+	ErrParseBadSection:        CodeBadSection,
+	ErrParseTextMismatch:      CodeTextMismatch,
+}
+
+// DocumentsFailedError is returned by handleMain when `--keep-going` skipped some documents
+type DocumentsFailedError struct {
+	Failed int
+	Total  int
+}
+
+func (e *DocumentsFailedError) Error() string {
+	return fmt.Sprintf(ErrDocumentsFailed, e.Failed, e.Total)
+}
+
+type DocumentError struct {
+	File         string `json:"file"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	AnnotationID string `json:"annotation_id,omitempty"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+}
+
+type ErrorReport struct {
+	Documents int `json:"documents"`
+	Converted int `json:"converted"`
+	Failed    int `json:"failed"`
+	// FailedRecords counts the segments and windows left out of documents that were otherwise converted
+	FailedRecords int             `json:"failed_records,omitempty"`
+	Errors        []DocumentError `json:"errors"`
+	// Conflicts lists the overlapping entities dropped by `--overlap`
+	Conflicts []OverlapConflict `json:"conflicts,omitempty"`
+	// Segmented lists the entities dropped or split by `--segment`
+	Segmented []SegmentedEntity `json:"segmented,omitempty"`
+	// Types counts the entity types converted without an `annotation.conf` filter, with `--all-types`
+	// or when the documents have no conf
+	Types map[string]int `json:"types,omitempty"`
+
+	// failedDocs holds the documents already counted as failed
+	failedDocs map[string]bool
+}
+
+func NewErrorReport(documents int) *ErrorReport {
+	return &ErrorReport{Documents: documents, Converted: documents, Errors: []DocumentError{}, failedDocs: make(map[string]bool)}
+}
+
+// Add records the failure of the document at path, a document is counted as failed once whatever the number of
+// its errors
+func (r *ErrorReport) Add(path string, err error) {
+	r.addError(path, err)
+	if !r.failedDocs[path] {
+		r.failedDocs[path] = true
+		r.Converted--
+		r.Failed++
+	}
+}
+
+// AddRecord records the failure of a record of the document at path, the document fails with its first record
+func (r *ErrorReport) AddRecord(path string, err error) {
+	r.Add(path, err)
+	r.FailedRecords++
+}
+
+func (r *ErrorReport) addError(path string, err error) {
+	docErr := DocumentError{File: path, Code: CodeUnknown, Message: err.Error()}
+
+	var parseErr *ParseError
+	var pathErr *os.PathError
+	var unpairedErr *UnpairedFileError
+	switch {
+	case errors.As(err, &parseErr):
+		if parseErr.Path != "" {
+			docErr.File = parseErr.Path
+		}
+		docErr.Line = parseErr.Line
+		docErr.Column = parseErr.Column
+		docErr.AnnotationID = parseErr.ID
+		docErr.Code = errorCodes[parseErr.Kind]
+		docErr.Message = parseErr.Message()
+	case errors.As(err, &unpairedErr):
+		docErr.File = unpairedErr.Path
+		docErr.Code = CodeUnpaired
+	case errors.As(err, &pathErr):
+		docErr.File = pathErr.Path
+		docErr.Code = CodeIO
+	}
+
+	r.Errors = append(r.Errors, docErr)
+}
+
+// AddType counts an entity converted without filtering its type
+func (r *ErrorReport) AddType(name string) {
+	if r.Types == nil {
+		r.Types = make(map[string]int)
+	}
+	r.Types[name]++
+}
+
+// TypeCounts formats the types converted without filtering, as `GPE (2), Person (3)`
+func (r *ErrorReport) TypeCounts() string {
+	counts := []string{}
+	for _, name := range sortedKeys(r.Types) {
+		counts = append(counts, fmt.Sprintf("%s (%d)", name, r.Types[name]))
+	}
+	return strings.Join(counts, ", ")
+}
+
+// Err returns a *DocumentsFailedError if any document failed, nil otherwise
+func (r *ErrorReport) Err() error {
+	if r.Failed == 0 {
+		return nil
+	}
+	return &DocumentsFailedError{r.Failed, r.Documents}
+}
+
+func (r *ErrorReport) Write(path string, perm os.FileMode) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), perm)
+}
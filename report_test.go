@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type KeepGoingSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *KeepGoingSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "keep-going")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *KeepGoingSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *KeepGoingSuite) TestKeepGoing() {
+	opts := Options{
+		AnnFiles:    "./testData/news/000-introduction.ann,./testData/invalid-files/invalid-ann-tab/030-login.ann",
+		TxtFiles:    "./testData/news/000-introduction.txt,./testData/news/030-login.txt",
+		ConfFile:    "./testData/news/annotation.conf",
+		OutputFile:  filepath.Join(suite.TmpDir, "out.jsonl"),
+		ErrorReport: filepath.Join(suite.TmpDir, "report.json"),
+	}
+
+	err := handleMain(opts)
+	suite.NotNil(err)
+	var failed *DocumentsFailedError
+	suite.False(errors.As(err, &failed))
+
+	opts.KeepGoing = true
+	err = handleMain(opts)
+	suite.True(errors.As(err, &failed))
+	suite.Equal(1, failed.Failed)
+	suite.Equal(2, failed.Total)
+
+	output, err := ioutil.ReadFile(opts.OutputFile)
+	suite.Nil(err)
+	suite.Contains(string(output), "[418,426,\"Organization\"]")
+
+	reportData, err := ioutil.ReadFile(opts.ErrorReport)
+	suite.Nil(err)
+	report := ErrorReport{}
+	suite.Nil(json.Unmarshal(reportData, &report))
+	suite.Equal(2, report.Documents)
+	suite.Equal(1, report.Converted)
+	suite.Equal(1, report.Failed)
+	suite.Equal([]DocumentError{{
+		File:         "./testData/invalid-files/invalid-ann-tab/030-login.ann",
+		Line:         1,
+		Column:       1,
+		AnnotationID: "T1",
+		Code:         CodeBadFormatTab,
+		Message:      ErrBadFormatTab,
+	}}, report.Errors)
+}
+
+func (suite *KeepGoingSuite) TestMissingFile() {
+	report := NewErrorReport(1)
+	_, err := os.Open("./testData/news/does-not-exist.ann")
+	report.Add("./testData/news/does-not-exist.ann", err)
+
+	suite.Equal(CodeIO, report.Errors[0].Code)
+	suite.Equal(0, report.Converted)
+	suite.NotNil(report.Err())
+}
+
+func (suite *KeepGoingSuite) TestRecordFailures() {
+	report := NewErrorReport(2)
+	spanErr := &ParseError{ID: "T1", Kind: ErrParseBadSpan}
+	report.AddRecord("a.ann", spanErr)
+	report.AddRecord("a.ann", spanErr)
+
+	suite.Equal(1, report.Failed)
+	suite.Equal(1, report.Converted)
+	suite.Equal(2, report.FailedRecords)
+	suite.Len(report.Errors, 2)
+	suite.EqualError(report.Err(), "1 of 2 documents failed to convert")
+}
+
+func (suite *KeepGoingSuite) TestUnpairedFiles() {
+	collection := filepath.Join(suite.TmpDir, "collection")
+	suite.Nil(os.Mkdir(collection, 0700))
+	for _, name := range []string{"annotation.conf", "000-introduction.ann", "000-introduction.txt", "030-login.txt"} {
+		data, err := ioutil.ReadFile(filepath.Join("./testData/news", name))
+		suite.Nil(err)
+		suite.Nil(ioutil.WriteFile(filepath.Join(collection, name), data, 0600))
+	}
+	opts := Options{FolderPath: collection, OutputFile: filepath.Join(suite.TmpDir, "out.jsonl"), ErrorReport: filepath.Join(suite.TmpDir, "report.json")}
+	suite.EqualError(handleMain(opts), filepath.Join(collection, "030-login.ann")+" file does not exist")
+
+	opts.KeepGoing = true
+	var failed *DocumentsFailedError
+	suite.True(errors.As(handleMain(opts), &failed))
+	suite.Equal(1, failed.Failed)
+	suite.Equal(2, failed.Total)
+
+	reportData, err := ioutil.ReadFile(opts.ErrorReport)
+	suite.Nil(err)
+	report := ErrorReport{}
+	suite.Nil(json.Unmarshal(reportData, &report))
+	suite.Equal(1, report.Converted)
+	suite.Equal([]DocumentError{{
+		File:    filepath.Join(collection, "030-login.txt"),
+		Code:    CodeUnpaired,
+		Message: filepath.Join(collection, "030-login.ann") + " file does not exist",
+	}}, report.Errors)
+
+	// An excluded file is not missing its pair
+	opts.Exclude = []string{"030-*"}
+	opts.OverWrite = true
+	suite.Nil(handleMain(opts))
+}
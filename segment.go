@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	SegmentSentence  = "sentence"
+	SegmentParagraph = "paragraph"
+
+	CrossingDrop  = "drop"
+	CrossingSplit = "split"
+
+	ErrSegmentUnknown      = "unknown segmentation: %s, expected `sentence` or `paragraph`"
+	ErrCrossingUnknown     = "unknown crossing entity handling: %s, expected `drop` or `split`"
+	InfoCrossingEntity     = "%s: %s crosses a %s boundary, %s"
+	InfoZeroWidthEntity    = "%s: %s is empty, dropped"
+	infoCrossingDropped    = "dropped"
+	infoCrossingSplit      = "split"
+	sentenceClosingQuotes  = "\"')]}»’”"
+	sentenceEndPunctuation = ".!?"
+)
+
+func ValidateSegment(segment, crossing string) error {
+	if segment != SegmentSentence && segment != SegmentParagraph {
+		return fmt.Errorf(ErrSegmentUnknown, segment)
+	}
+	if crossing != CrossingDrop && crossing != CrossingSplit {
+		return fmt.Errorf(ErrCrossingUnknown, crossing)
+	}
+	return nil
+}
+
+// SegmentedEntity is an entity that did not fit in a single segment: it crossed a boundary and was dropped or
+// split, or it was empty and belonged to no segment
+type SegmentedEntity struct {
+	File    string         `json:"file"`
+	Code    string         `json:"code"`
+	Segment string         `json:"segment"`
+	Action  string         `json:"action"`
+	Entity  ConflictEntity `json:"entity"`
+}
+
+func (e SegmentedEntity) String() string {
+	if e.Code == CodeZeroWidth {
+		return fmt.Sprintf(InfoZeroWidthEntity, e.File, e.Entity)
+	}
+	return fmt.Sprintf(InfoCrossingEntity, e.File, e.Entity, e.Segment, e.Action)
+}
+
+// textChars returns the characters of the text the way the brat offsets count them, without `\r`
+func textChars(text string) []rune {
+	chars := []rune{}
+	for _, r := range text {
+		if r != '\r' {
+			chars = append(chars, r)
+		}
+	}
+	return chars
+}
+
+// SegmentText splits the text in paragraphs, separated by blank lines, or sentences. The regex splitter ends a
+// sentence with `.`, `!` or `?` followed by a space, or at the end of a paragraph, the newline splitter at every
+// line break. The spans are character offsets, without the whitespace around every segment.
+func SegmentText(text, segment, splitter string) []Span {
+	chars := textChars(text)
+	boundaries := []int{}
+
+	for i := 0; i < len(chars); i++ {
+		if chars[i] == '\n' && segment == SegmentSentence && splitter == SplitterNewline {
+			boundaries = append(boundaries, i)
+			continue
+		}
+		if chars[i] == '\n' {
+			// A blank line, possibly holding spaces, ends a paragraph
+			j := i + 1
+			for j < len(chars) && chars[j] != '\n' && unicode.IsSpace(chars[j]) {
+				j++
+			}
+			if j < len(chars) && chars[j] == '\n' {
+				boundaries = append(boundaries, i)
+				i = j - 1
+			}
+			continue
+		}
+		if segment == SegmentSentence && splitter != SplitterNewline && strings.ContainsRune(sentenceEndPunctuation, chars[i]) {
+			j := i + 1
+			for j < len(chars) && strings.ContainsRune(sentenceClosingQuotes, chars[j]) {
+				j++
+			}
+			if j == len(chars) || unicode.IsSpace(chars[j]) {
+				boundaries = append(boundaries, j)
+				i = j - 1
+			}
+		}
+	}
+	boundaries = append(boundaries, len(chars))
+
+	segments := []Span{}
+	begin := 0
+	for _, end := range boundaries {
+		b, e := begin, end
+		for b < e && unicode.IsSpace(chars[b]) {
+			b++
+		}
+		for e > b && unicode.IsSpace(chars[e-1]) {
+			e--
+		}
+		if b < e {
+			segments = append(segments, Span{b, e})
+		}
+		begin = end
+	}
+	return segments
+}
+
+// Segment splits the document into one document per segment, with the entity offsets rebased on the segment.
+// Entities crossing a segment boundary are dropped, or with CrossingSplit cut at the boundaries, empty entities
+// are dropped. Both are returned so they can be reported.
+func (d *Document) Segment(segment, crossing, splitter string) ([]*Document, []SegmentedEntity) {
+	chars := textChars(d.Data)
+	spans := SegmentText(d.Data, segment, splitter)
+	segmented := []*Document{}
+	reported := []SegmentedEntity{}
+
+	for _, span := range spans {
+		begin := span.Begin
+		meta := d.Meta
+		meta.SourceDoc = d.TxtPath
+		meta.SourceOffset = &begin
+		segmented = append(segmented, &Document{
+			AnnPath:   d.AnnPath,
+			TxtPath:   d.TxtPath,
+			Data:      string(chars[span.Begin:span.End]),
+			Entities:  []NumberAcharyaEntity{},
+			Meta:      meta,
+			Tokenizer: d.Tokenizer,
+			Splitter:  d.Splitter,
+		})
+	}
+
+	for _, ent := range d.Entities {
+		pieces := []int{}
+		inside := false
+		for i, span := range spans {
+			if ent.Entity.Begin < span.End && span.Begin < ent.Entity.End {
+				pieces = append(pieces, i)
+				inside = ent.Entity.Begin >= span.Begin && ent.Entity.End <= span.End
+			}
+		}
+		if len(pieces) == 1 && inside {
+			span := spans[pieces[0]]
+			ent.Entity.Begin -= span.Begin
+			ent.Entity.End -= span.Begin
+			segmented[pieces[0]].Entities = append(segmented[pieces[0]].Entities, ent)
+			continue
+		}
+
+		notice := SegmentedEntity{File: d.AnnPath, Code: CodeCrossing, Segment: segment, Action: infoCrossingDropped, Entity: newConflictEntity(ent)}
+		if ent.Entity.Begin == ent.Entity.End {
+			notice.Code = CodeZeroWidth
+		}
+		if notice.Code == CodeZeroWidth || crossing == CrossingDrop || len(pieces) == 0 {
+			reported = append(reported, notice)
+			continue
+		}
+		notice.Action = infoCrossingSplit
+		reported = append(reported, notice)
+		for _, i := range pieces {
+			piece := ent
+			piece.Entity.Begin = maxInt(ent.Entity.Begin, spans[i].Begin) - spans[i].Begin
+			piece.Entity.End = minInt(ent.Entity.End, spans[i].End) - spans[i].Begin
+			segmented[i].Entities = append(segmented[i].Entities, piece)
+		}
+	}
+	return segmented, reported
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SegmentSuite struct {
+	suite.Suite
+}
+
+func (suite *SegmentSuite) TestSegmentText() {
+	text := "Mr Smith went to Washington. He said \"hi!\" Then left?\r\n\r\n  Second paragraph.\n \nThird"
+	suite.Equal([]Span{{0, 28}, {29, 42}, {43, 53}, {57, 74}, {77, 82}}, SegmentText(text, SegmentSentence, SplitterRegex))
+	suite.Equal([]Span{{0, 53}, {57, 74}, {77, 82}}, SegmentText(text, SegmentParagraph, SplitterRegex))
+	suite.Empty(SegmentText(" \n\n ", SegmentParagraph, SplitterRegex))
+}
+
+func (suite *SegmentSuite) TestSegmentDocument() {
+	doc := &Document{
+		AnnPath: "doc.ann",
+		TxtPath: "doc.txt",
+		Data:    "Sony is in Tokyo. Paris is in France.",
+		Entities: []NumberAcharyaEntity{
+			{1, AcharyaEntity{0, 4, "Organization"}},
+			{2, AcharyaEntity{11, 16, "GPE"}},
+			{3, AcharyaEntity{11, 23, "GPE"}},
+			{4, AcharyaEntity{30, 36, "GPE"}},
+		},
+		Meta: RecordMeta{Conf: "annotation.conf"},
+	}
+
+	segments, reported := doc.Segment(SegmentSentence, CrossingDrop, SplitterRegex)
+	suite.Len(segments, 2)
+	suite.Equal([]SegmentedEntity{{"doc.ann", CodeCrossing, SegmentSentence, "dropped", ConflictEntity{"T3", "GPE", 11, 23}}}, reported)
+	suite.Equal("Sony is in Tokyo.", segments[0].Data)
+	suite.Equal([]NumberAcharyaEntity{{1, AcharyaEntity{0, 4, "Organization"}}, {2, AcharyaEntity{11, 16, "GPE"}}}, segments[0].Entities)
+	suite.Equal("Paris is in France.", segments[1].Data)
+	suite.Equal([]NumberAcharyaEntity{{4, AcharyaEntity{12, 18, "GPE"}}}, segments[1].Entities)
+	suite.Equal("doc.txt", segments[1].Meta.SourceDoc)
+	suite.Equal(18, *segments[1].Meta.SourceOffset)
+	suite.Equal("annotation.conf", segments[1].Meta.Conf)
+
+	segments, reported = doc.Segment(SegmentSentence, CrossingSplit, SplitterRegex)
+	suite.Equal("split", reported[0].Action)
+	suite.Equal([]NumberAcharyaEntity{{1, AcharyaEntity{0, 4, "Organization"}}, {2, AcharyaEntity{11, 16, "GPE"}}, {3, AcharyaEntity{11, 17, "GPE"}}}, segments[0].Entities)
+	suite.Equal([]NumberAcharyaEntity{{3, AcharyaEntity{0, 5, "GPE"}}, {4, AcharyaEntity{12, 18, "GPE"}}}, segments[1].Entities)
+
+	record, err := segments[1].Acharya()
+	suite.Nil(err)
+	suite.Equal("{\"Data\":\"Paris is in France.\",\"Entities\":[[0,5,\"GPE\"],[12,18,\"GPE\"]],"+
+		"\"Meta\":{\"conf\":\"annotation.conf\",\"source_doc\":\"doc.txt\",\"source_offset\":18}}\n", record)
+}
+
+func (suite *SegmentSuite) TestDroppedEntitiesAreReported() {
+	dir, err := ioutil.TempDir("", "segment")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "annotation.conf"), []byte("[entities]\nPerson\n[relations]\n[events]\n[attributes]\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("John left. Mary stayed."), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.ann"), []byte("T1\tPerson 0 15\tJohn left. Mary\nT2\tPerson 11 11\t\n"), 0600))
+
+	report := filepath.Join(dir, "report.json")
+	output := filepath.Join(dir, "out.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: dir, OutputFile: output, Segment: SegmentSentence, Crossing: CrossingDrop, Splitter: SplitterRegex, ErrorReport: report}))
+	data, err := ioutil.ReadFile(report)
+	suite.Nil(err)
+	errorReport := ErrorReport{}
+	suite.Nil(json.Unmarshal(data, &errorReport))
+	suite.Equal(1, errorReport.Converted)
+	suite.Equal([]SegmentedEntity{
+		{filepath.Join(dir, "a.ann"), CodeCrossing, SegmentSentence, "dropped", ConflictEntity{"T1", "Person", 0, 15}},
+		{filepath.Join(dir, "a.ann"), CodeZeroWidth, SegmentSentence, "dropped", ConflictEntity{"T2", "Person", 11, 11}},
+	}, errorReport.Segmented)
+}
+
+func (suite *SegmentSuite) TestBadSpanIsReported() {
+	dir, err := ioutil.TempDir("", "segment")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "annotation.conf"), []byte("[entities]\nPerson\n[relations]\n[events]\n[attributes]\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("John left. Mary stayed."), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.ann"), []byte("T1\tPerson 0 4\tJohn\nT2\tPerson 40 44\tMary\n"), 0600))
+
+	report := filepath.Join(dir, "report.json")
+	output := filepath.Join(dir, "out.jsonl")
+	err = handleMain(Options{FolderPath: dir, OutputFile: output, Segment: SegmentSentence, Crossing: CrossingDrop, Splitter: SplitterRegex, KeepGoing: true, ErrorReport: report})
+	var failed *DocumentsFailedError
+	suite.True(errors.As(err, &failed))
+	data, err := ioutil.ReadFile(report)
+	suite.Nil(err)
+	suite.Contains(string(data), CodeBadSpan)
+	data, err = ioutil.ReadFile(output)
+	suite.Nil(err)
+	suite.Empty(data)
+}
+
+func (suite *SegmentSuite) TestValidateSegment() {
+	suite.Nil(ValidateSegment(SegmentParagraph, CrossingSplit))
+	suite.EqualError(ValidateSegment("line", CrossingDrop), "unknown segmentation: line, expected `sentence` or `paragraph`")
+	suite.EqualError(ValidateSegment(SegmentSentence, "keep"), "unknown crossing entity handling: keep, expected `drop` or `split`")
+}
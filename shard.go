@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrShardBadBytes          = "invalid shard size: %s, expected a number of bytes such as `100MB`"
+	ErrValidateShardSize      = "`--shard-size` should be a positive number of records, received %d"
+	ErrValidateShardNoFile    = "sharded output is written to numbered files, specify the output file with `--output`"
+	manifestSuffix            = ".manifest.json"
+	shardNumberFormat         = "-%05d"
+	InfoSuccessfullyGenShards = "wrote %d records in %d shards: %s"
+)
+
+// byteUnits are the suffixes of `--shard-bytes`, in powers of 1024
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30}, {"G", 1 << 30}, {"MB", 1 << 20}, {"M", 1 << 20}, {"KB", 1 << 10}, {"K", 1 << 10}, {"B", 1},
+}
+
+// ParseByteSize parses a size such as `100MB`, `512K` or `2048`, the units are powers of 1024
+func ParseByteSize(size string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(size))
+	unit := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf(ErrShardBadBytes, size)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// sharded reports whether the outputs are written in shards
+func (o Options) sharded() bool {
+	return o.ShardSize > 0 || o.ShardBytes != ""
+}
+
+func ValidateShards(opts Options, outputs []OutputSpec) error {
+	if opts.ShardSize < 0 {
+		return fmt.Errorf(ErrValidateShardSize, opts.ShardSize)
+	}
+	if opts.ShardBytes != "" {
+		if _, err := ParseByteSize(opts.ShardBytes); err != nil {
+			return err
+		}
+	}
+	if opts.sharded() && writesStdout(outputs) {
+		return errors.New(ErrValidateShardNoFile)
+	}
+	return nil
+}
+
+// ShardOutputPath numbers the output file, `out.jsonl.gz` has the shards `out-00000.jsonl.gz`, `out-00001.jsonl.gz`...
+func ShardOutputPath(outputFile string, shard int) string {
+	compressionExt := CompressionExt(outputFile)
+	outputFile = strings.TrimSuffix(outputFile, compressionExt)
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + fmt.Sprintf(shardNumberFormat, shard) + ext + compressionExt
+}
+
+// ManifestPath is the manifest of the shards of the output file, `out.jsonl.manifest.json` for `out.jsonl.gz`
+func ManifestPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, CompressionExt(outputFile)) + manifestSuffix
+}
+
+// ShardInfo describes a shard file, its checksum is the SHA-256 of the file as written, compressed or not
+type ShardInfo struct {
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// ShardManifest lists the shards of an output, their files are relative to the manifest
+type ShardManifest struct {
+	Format  string      `json:"format"`
+	Records int         `json:"records"`
+	Shards  []ShardInfo `json:"shards"`
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// shardPlanner groups the documents in shards of at most size records and maxBytes bytes of uncompressed output,
+// a zero limit is not applied. A document larger than maxBytes gets a shard of its own.
+type shardPlanner struct {
+	size     int
+	maxBytes int64
+	count    int
+	bytes    int64
+}
+
+// Next adds a document of n bytes and reports whether it starts a new shard
+func (p *shardPlanner) Next(n int64) bool {
+	start := p.count == 0 || (p.size > 0 && p.count >= p.size) || (p.maxBytes > 0 && p.bytes+n > p.maxBytes)
+	if start {
+		p.count, p.bytes = 0, 0
+	}
+	p.count++
+	p.bytes += n
+	return start
+}
+
+// fileChecksum returns the size and SHA-256 of the file
+func fileChecksum(name string) (int64, string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// shardWriter writes the documents of an output in numbered shard files, with a manifest listing them. The shards
+// are renamed into place together by Commit.
+type shardWriter struct {
+	output  OutputSpec
+	mode    OutputMode
+	planner shardPlanner
+	// render renders every document to buffer, to measure it before it is written to its shard
+	render *documentWriter
+	buffer *bytes.Buffer
+	// shards are the finished shards, current is the shard being written
+	shards   []*outputStream
+	current  *outputStream
+	manifest ShardManifest
+}
+
+func newShardWriter(opts Options, output OutputSpec, mode OutputMode, tokenizer, splitter string) (*shardWriter, error) {
+	maxBytes := int64(0)
+	if opts.ShardBytes != "" {
+		var err error
+		if maxBytes, err = ParseByteSize(opts.ShardBytes); err != nil {
+			return nil, err
+		}
+	}
+	buffer := &bytes.Buffer{}
+	render, err := newDocumentWriter(buffer, output.Format, tokenizer, splitter)
+	if err != nil {
+		return nil, err
+	}
+	return &shardWriter{
+		output:   output,
+		mode:     mode,
+		planner:  shardPlanner{size: opts.ShardSize, maxBytes: maxBytes},
+		render:   render,
+		buffer:   buffer,
+		manifest: ShardManifest{Format: output.Format, Shards: []ShardInfo{}},
+	}, nil
+}
+
+func (s *shardWriter) WriteDocument(doc *Document, record string) error {
+	s.buffer.Reset()
+	if err := s.render.WriteDocument(doc, record); err != nil {
+		return err
+	}
+	if s.planner.Next(int64(s.buffer.Len())) {
+		if err := s.nextShard(); err != nil {
+			return err
+		}
+		// The first document of a shard starts a CoNLL document even when it continues the previous one
+		s.buffer.Reset()
+		s.render.reset()
+		if err := s.render.WriteDocument(doc, record); err != nil {
+			return err
+		}
+	}
+	s.manifest.Records++
+	s.manifest.Shards[len(s.manifest.Shards)-1].Records++
+	_, err := s.current.Write(s.buffer.Bytes())
+	return err
+}
+
+// nextShard finishes the current shard and opens the next one
+func (s *shardWriter) nextShard() error {
+	if s.current != nil {
+		if err := s.current.finish(); err != nil {
+			return err
+		}
+		s.shards = append(s.shards, s.current)
+		s.current = nil
+	}
+	shardFile := ShardOutputPath(s.output.File, len(s.manifest.Shards))
+	stream, err := openOutputStream(shardFile, s.mode)
+	if err != nil {
+		return err
+	}
+	s.current = stream
+	s.manifest.Shards = append(s.manifest.Shards, ShardInfo{File: filepath.Base(shardFile)})
+	return nil
+}
+
+// Commit renames the shards into place and writes the manifest with their size and checksum
+func (s *shardWriter) Commit() error {
+	if s.current != nil {
+		if err := s.current.finish(); err != nil {
+			s.Abort()
+			return err
+		}
+		s.shards = append(s.shards, s.current)
+		s.current = nil
+	}
+	for i, shard := range s.shards {
+		if err := shard.file.rename(); err != nil {
+			for _, left := range s.shards[i+1:] {
+				left.Abort()
+			}
+			return err
+		}
+		size, checksum, err := fileChecksum(shard.file.name)
+		if err != nil {
+			return err
+		}
+		s.manifest.Shards[i].Bytes = size
+		s.manifest.Shards[i].SHA256 = checksum
+	}
+	if s.mode.OverWrite {
+		if err := removeStaleShards(s.output.File, len(s.shards)); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestFile := ManifestPath(s.output.File)
+	err = handleOutputStream(manifestFile, s.mode, func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, InfoSuccessfullyGenShards+"\n", s.manifest.Records, len(s.manifest.Shards), manifestFile)
+	return nil
+}
+
+// removeStaleShards removes the shards numbered from `from` on, left over from a previous run with more shards
+func removeStaleShards(outputFile string, from int) error {
+	for shard := from; ; shard++ {
+		err := os.Remove(ShardOutputPath(outputFile, shard))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *shardWriter) Abort() {
+	for _, shard := range s.shards {
+		shard.Abort()
+	}
+	if s.current != nil {
+		s.current.Abort()
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ShardSuite struct {
+	suite.Suite
+	TmpDir string
+}
+
+func (suite *ShardSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "shard")
+	suite.Nil(err)
+	suite.TmpDir = dir
+}
+
+func (suite *ShardSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+}
+
+func (suite *ShardSuite) readManifest(path string) ShardManifest {
+	data, err := ioutil.ReadFile(path)
+	suite.Nil(err)
+	manifest := ShardManifest{}
+	suite.Nil(json.Unmarshal(data, &manifest))
+	return manifest
+}
+
+func (suite *ShardSuite) TestParseByteSize() {
+	for size, expected := range map[string]int64{"100MB": 100 << 20, "512k": 512 << 10, "2048": 2048, "1.5G": 3 << 29, "10 B": 10} {
+		n, err := ParseByteSize(size)
+		suite.Nil(err, size)
+		suite.Equal(expected, n, size)
+	}
+	for _, size := range []string{"", "MB", "-1MB", "0", "ten"} {
+		_, err := ParseByteSize(size)
+		suite.EqualError(err, "invalid shard size: "+size+", expected a number of bytes such as `100MB`")
+	}
+}
+
+func (suite *ShardSuite) TestShardPaths() {
+	suite.Equal("out-00000.jsonl", ShardOutputPath("out.jsonl", 0))
+	suite.Equal(filepath.Join("dir", "out.train-00012.jsonl.gz"), ShardOutputPath(filepath.Join("dir", "out.train.jsonl.gz"), 12))
+	suite.Equal("out-00001", ShardOutputPath("out", 1))
+	suite.Equal("out.jsonl.manifest.json", ManifestPath("out.jsonl.zst"))
+}
+
+func (suite *ShardSuite) plan(size int, maxBytes int64, records ...string) []int {
+	planner := shardPlanner{size: size, maxBytes: maxBytes}
+	starts := []int{}
+	for i, record := range records {
+		if planner.Next(int64(len(record))) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+func (suite *ShardSuite) TestShardPlanner() {
+	records := []string{"aaaa\n", "bb\n", "cccccc\n", "d\n", "eeeeeeeeeeee\n"}
+	suite.Equal([]int{0, 2, 4}, suite.plan(2, 0, records...))
+
+	// A document larger than the limit has a shard of its own
+	suite.Equal([]int{0, 2, 4}, suite.plan(0, 10, records...))
+	suite.Equal([]int{0, 1, 2, 3, 4}, suite.plan(1, 1000, records...))
+	suite.Empty(suite.plan(2, 0))
+}
+
+func (suite *ShardSuite) TestShardedOutput() {
+	expected := filepath.Join(suite.TmpDir, "expected.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: "./testData/news", OutputFile: expected}))
+	original, err := ioutil.ReadFile(expected)
+	suite.Nil(err)
+	total := strings.Count(string(original), "\n")
+
+	output := filepath.Join(suite.TmpDir, "out.jsonl")
+	opts := Options{FolderPath: "./testData/news", OutputFile: output, ShardSize: 3}
+	suite.Nil(ValidateFlags(opts))
+	suite.Nil(handleMain(opts))
+
+	manifest := suite.readManifest(filepath.Join(suite.TmpDir, "out.jsonl.manifest.json"))
+	suite.Equal(FormatAcharya, manifest.Format)
+	suite.Equal(total, manifest.Records)
+	suite.Equal((total+2)/3, len(manifest.Shards))
+
+	joined := ""
+	for i, shard := range manifest.Shards {
+		suite.Equal(filepath.Base(ShardOutputPath(output, i)), shard.File)
+		data, err := ioutil.ReadFile(filepath.Join(suite.TmpDir, shard.File))
+		suite.Nil(err)
+		suite.Equal(shard.Records, strings.Count(string(data), "\n"))
+		size, checksum, err := fileChecksum(filepath.Join(suite.TmpDir, shard.File))
+		suite.Nil(err)
+		suite.Equal(shard.Bytes, size)
+		suite.Equal(shard.SHA256, checksum)
+		joined += string(data)
+	}
+	suite.Equal(string(original), joined)
+	_, err = os.Stat(output)
+	suite.True(os.IsNotExist(err))
+
+	// The shards are not overwritten without --force
+	suite.NotNil(handleMain(opts))
+	opts.OverWrite = true
+	suite.Nil(handleMain(opts))
+
+	// A rerun with fewer shards removes the shards left over
+	opts.ShardSize = total
+	suite.Nil(handleMain(opts))
+	manifest = suite.readManifest(filepath.Join(suite.TmpDir, "out.jsonl.manifest.json"))
+	suite.Equal(1, len(manifest.Shards))
+	_, err = os.Stat(ShardOutputPath(output, 1))
+	suite.True(os.IsNotExist(err))
+
+	// The shard size limits the uncompressed output
+	opts = Options{FolderPath: "./testData/news", Outputs: []string{filepath.Join(suite.TmpDir, "bytes.jsonl.gz")}, ShardBytes: "4KB"}
+	suite.Nil(handleMain(opts))
+	manifest = suite.readManifest(filepath.Join(suite.TmpDir, "bytes.jsonl.manifest.json"))
+	suite.True(len(manifest.Shards) > 1)
+	suite.Equal("bytes-00000.jsonl.gz", manifest.Shards[0].File)
+}
+
+func (suite *ShardSuite) TestValidateShards() {
+	suite.EqualError(ValidateFlags(Options{FolderPath: "./testData/news", ShardSize: 10}), ErrValidateShardNoFile)
+	suite.EqualError(ValidateFlags(Options{FolderPath: "./testData/news", Outputs: []string{"out.jsonl", "conll=-"}, ShardBytes: "1MB"}), ErrValidateShardNoFile)
+	suite.EqualError(ValidateFlags(Options{FolderPath: "./testData/news", OutputFile: "out.jsonl", ShardSize: -1}), "`--shard-size` should be a positive number of records, received -1")
+	suite.NotNil(ValidateFlags(Options{FolderPath: "./testData/news", OutputFile: "out.jsonl", ShardBytes: "lots"}))
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrSplitBadRatio     = "invalid split ratio: %s"
+	ErrSplitRatioSum     = "split ratios should add up to 1, received %s"
+	ErrSplitDuplicate    = "split name used more than once: %s"
+	ErrValidateSplitNoOp = "`--split` writes one file per split, specify the output file with `--output`"
+)
+
+// defaultSplitNames are used when `--split` only lists the ratios
+var defaultSplitNames = []string{"train", "dev", "test"}
+
+type SplitRatio struct {
+	Name  string
+	Ratio float64
+}
+
+// ParseSplit parses `--split`, either `0.8,0.1,0.1` or `train=0.8,dev=0.1,test=0.1`
+func ParseSplit(spec string) ([]SplitRatio, error) {
+	ratios := []SplitRatio{}
+	seen := make(map[string]bool)
+	total := 0.0
+
+	for i, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		name := ""
+		if i < len(defaultSplitNames) {
+			name = defaultSplitNames[i]
+		}
+		if nameAndRatio := strings.SplitN(part, "=", 2); len(nameAndRatio) == 2 {
+			name = strings.TrimSpace(nameAndRatio[0])
+			part = strings.TrimSpace(nameAndRatio[1])
+		}
+		if name == "" {
+			return nil, fmt.Errorf(ErrSplitBadRatio, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf(ErrSplitDuplicate, name)
+		}
+		seen[name] = true
+
+		ratio, err := strconv.ParseFloat(part, 64)
+		if err != nil || ratio < 0 {
+			return nil, fmt.Errorf(ErrSplitBadRatio, part)
+		}
+		total += ratio
+		ratios = append(ratios, SplitRatio{name, ratio})
+	}
+
+	if total < 0.999 || total > 1.001 {
+		return nil, fmt.Errorf(ErrSplitRatioSum, spec)
+	}
+	return ratios, nil
+}
+
+// documentGroup is a set of documents that always end up in the same split
+type documentGroup struct {
+	docs   []int
+	counts map[string]int
+}
+
+// SplitDocuments partitions the documents by ratio and returns the indices of the documents of every split,
+// in their original order. The same seed always gives the same partition. The segments and windows of a
+// document always end up in the same split. With stratify the entity types are spread across the splits
+// following the ratios, with groupByDir documents of the same directory stay together.
+func SplitDocuments(docs []*Document, ratios []SplitRatio, seed int64, stratify, groupByDir bool) [][]int {
+	groups := []*documentGroup{}
+	groupOf := make(map[string]*documentGroup)
+	typeTotals := make(map[string]int)
+
+	for i, doc := range docs {
+		key := strconv.Itoa(i)
+		if doc.Meta.SourceDoc != "" {
+			key = "doc:" + doc.Meta.SourceDoc
+		}
+		if groupByDir {
+			key = filepath.Dir(doc.AnnPath)
+		}
+		group, ok := groupOf[key]
+		if !ok {
+			group = &documentGroup{counts: make(map[string]int)}
+			groupOf[key] = group
+			groups = append(groups, group)
+		}
+		group.docs = append(group.docs, i)
+		for _, ent := range doc.Entities {
+			group.counts[ent.Entity.Name]++
+			typeTotals[ent.Entity.Name]++
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	rnd.Shuffle(len(groups), func(i, j int) {
+		groups[i], groups[j] = groups[j], groups[i]
+	})
+
+	// rarest returns the entity type of the group with the fewest annotations in the whole collection
+	rarest := func(g *documentGroup) string {
+		rare := ""
+		for name := range g.counts {
+			if rare == "" || typeTotals[name] < typeTotals[rare] || (typeTotals[name] == typeTotals[rare] && name < rare) {
+				rare = name
+			}
+		}
+		return rare
+	}
+	if stratify {
+		// Groups holding the rarest entity types are placed first, while every split still has room for them
+		sort.SliceStable(groups, func(i, j int) bool {
+			return typeTotals[rarest(groups[i])] < typeTotals[rarest(groups[j])]
+		})
+	}
+
+	assigned := make([]int, len(ratios))
+	typeAssigned := make([]map[string]int, len(ratios))
+	for i := range typeAssigned {
+		typeAssigned[i] = make(map[string]int)
+	}
+
+	splits := make([][]int, len(ratios))
+	for _, group := range groups {
+		rare := rarest(group)
+		best := -1
+		bestTypeDemand, bestDemand := 0.0, 0.0
+		for i, ratio := range ratios {
+			if ratio.Ratio == 0 {
+				continue
+			}
+			// The split missing the most documents wins, with stratify the split missing the most
+			// annotations of the rarest type of the group goes first
+			demand := ratio.Ratio*float64(len(docs)) - float64(assigned[i])
+			typeDemand := 0.0
+			if stratify && rare != "" {
+				typeDemand = ratio.Ratio*float64(typeTotals[rare]) - float64(typeAssigned[i][rare])
+			}
+			if best == -1 || typeDemand > bestTypeDemand || (typeDemand == bestTypeDemand && demand > bestDemand) {
+				best = i
+				bestTypeDemand, bestDemand = typeDemand, demand
+			}
+		}
+
+		assigned[best] += len(group.docs)
+		for name, count := range group.counts {
+			typeAssigned[best][name] += count
+		}
+		splits[best] = append(splits[best], group.docs...)
+	}
+
+	for _, split := range splits {
+		sort.Ints(split)
+	}
+	return splits
+}
+
+// SplitOutputPath inserts the name of the split before the extension of the output file, `out.jsonl.gz` is split
+// in `out.train.jsonl.gz`
+func SplitOutputPath(outputFile, name string) string {
+	compressionExt := CompressionExt(outputFile)
+	outputFile = strings.TrimSuffix(outputFile, compressionExt)
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + "." + name + ext + compressionExt
+}
+
+// writeSplits writes the documents of every split to their own file of every output
+func writeSplits(opts Options, outputs []OutputSpec, docs []*Document, records []string, tokenizer, splitter string) error {
+	if writesStdout(outputs) {
+		return errors.New(ErrValidateSplitNoOp)
+	}
+	ratios, err := ParseSplit(opts.Split)
+	if err != nil {
+		return err
+	}
+
+	splits := SplitDocuments(docs, ratios, opts.Seed, opts.Stratify, opts.GroupByDir)
+	for i, split := range splits {
+		splitDocs := []*Document{}
+		splitRecords := []string{}
+		for _, doc := range split {
+			splitDocs = append(splitDocs, docs[doc])
+			splitRecords = append(splitRecords, records[doc])
+		}
+		splitOutputs := []OutputSpec{}
+		for _, output := range outputs {
+			splitOutputs = append(splitOutputs, OutputSpec{output.Format, SplitOutputPath(output.File, ratios[i].Name)})
+		}
+		if err := writeOutputs(opts, splitOutputs, splitDocs, splitRecords, tokenizer, splitter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SplitSuite struct {
+	suite.Suite
+}
+
+func splitTestDocuments(n int) []*Document {
+	docs := []*Document{}
+	for i := 0; i < n; i++ {
+		name := "Person"
+		if i%2 == 0 {
+			name = "GPE"
+		}
+		docs = append(docs, &Document{
+			AnnPath:  filepath.Join("dir"+string(rune('a'+i%4)), "doc.ann"),
+			Entities: []NumberAcharyaEntity{{i, AcharyaEntity{0, 1, name}}},
+		})
+	}
+	return docs
+}
+
+func (suite *SplitSuite) TestParseSplit() {
+	ratios, err := ParseSplit("0.8,0.1,0.1")
+	suite.Nil(err)
+	suite.Equal([]SplitRatio{{"train", 0.8}, {"dev", 0.1}, {"test", 0.1}}, ratios)
+
+	ratios, err = ParseSplit("train=0.7, valid=0.3")
+	suite.Nil(err)
+	suite.Equal([]SplitRatio{{"train", 0.7}, {"valid", 0.3}}, ratios)
+
+	_, err = ParseSplit("0.8,0.1")
+	suite.EqualError(err, "split ratios should add up to 1, received 0.8,0.1")
+	_, err = ParseSplit("0.5,abc,0.5")
+	suite.EqualError(err, "invalid split ratio: abc")
+	_, err = ParseSplit("a=0.5,a=0.5")
+	suite.EqualError(err, "split name used more than once: a")
+	_, err = ParseSplit("0.25,0.25,0.25,0.25")
+	suite.EqualError(err, "invalid split ratio: 0.25")
+}
+
+func (suite *SplitSuite) TestSplitDocuments() {
+	docs := splitTestDocuments(20)
+	ratios := []SplitRatio{{"train", 0.8}, {"dev", 0.1}, {"test", 0.1}}
+
+	splits := SplitDocuments(docs, ratios, 42, false, false)
+	suite.Len(splits[0], 16)
+	suite.Len(splits[1], 2)
+	suite.Len(splits[2], 2)
+	suite.Equal(splits, SplitDocuments(docs, ratios, 42, false, false))
+	suite.NotEqual(splits, SplitDocuments(docs, ratios, 7, false, false))
+
+	stratified := SplitDocuments(docs, ratios, 42, true, false)
+	for _, split := range stratified {
+		gpe := 0
+		for _, i := range split {
+			if docs[i].Entities[0].Entity.Name == "GPE" {
+				gpe++
+			}
+		}
+		suite.NotZero(gpe)
+	}
+
+	grouped := SplitDocuments(docs, []SplitRatio{{"train", 0.5}, {"test", 0.5}}, 42, false, true)
+	suite.Len(grouped[0], 10)
+	suite.Len(grouped[1], 10)
+	dirSplit := make(map[string]int)
+	for s, split := range grouped {
+		for _, i := range split {
+			dir := filepath.Dir(docs[i].AnnPath)
+			if prev, ok := dirSplit[dir]; ok {
+				suite.Equal(prev, s)
+			}
+			dirSplit[dir] = s
+		}
+	}
+}
+
+func (suite *SplitSuite) TestSplitOutputPath() {
+	suite.Equal("out.train.jsonl", SplitOutputPath("out.jsonl", "train"))
+	suite.Equal("out.train.jsonl.gz", SplitOutputPath("out.jsonl.gz", "train"))
+	suite.Equal(filepath.Join("dir", "out.dev"), SplitOutputPath(filepath.Join("dir", "out"), "dev"))
+}
+
+func (suite *SplitSuite) TestHandleMainSplit() {
+	dir, err := ioutil.TempDir("", "split")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "out.jsonl")
+	err = handleMain(Options{FolderPath: "./testData/news", OutputFile: output, Split: "train=0.6,test=0.4", Seed: 1})
+	suite.Nil(err)
+
+	records := 0
+	for _, name := range []string{"out.train.jsonl", "out.test.jsonl"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		suite.Nil(err)
+		records += strings.Count(string(data), "\n")
+	}
+	suite.Equal(13, records)
+
+	// The sentences of a document are never split apart
+	output = filepath.Join(dir, "segments.jsonl")
+	err = handleMain(Options{FolderPath: "./testData/news", OutputFile: output, Split: "train=0.6,test=0.4", Seed: 1, Segment: SegmentSentence, Crossing: CrossingDrop, Splitter: SplitterRegex})
+	suite.Nil(err)
+	splitOf := make(map[string]string)
+	for _, name := range []string{"train", "test"} {
+		data, err := ioutil.ReadFile(SplitOutputPath(output, name))
+		suite.Nil(err)
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			record := struct{ Meta RecordMeta }{}
+			suite.Nil(json.Unmarshal([]byte(line), &record))
+			suite.NotEmpty(record.Meta.SourceDoc)
+			if prev, ok := splitOf[record.Meta.SourceDoc]; ok {
+				suite.Equal(prev, name, record.Meta.SourceDoc)
+			}
+			splitOf[record.Meta.SourceDoc] = name
+		}
+	}
+	suite.Len(splitOf, 13)
+
+	err = ValidateFlags(Options{FolderPath: "./testData/news", Split: "0.8,0.2"})
+	suite.EqualError(err, ErrValidateSplitNoOp)
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const ErrUnknownAnnotation = "unknown annotation type"
+
+var ErrParseUnknownAnnotation = errors.New(ErrUnknownAnnotation)
+
+// Span is a character range of the txt data, End is exclusive
+type Span struct {
+	Begin int
+	End   int
+}
+
+// TextBound is a `T` annotation, it has several spans when it is discontinuous
+type TextBound struct {
+	ID    string
+	Type  string
+	Spans []Span
+	Text  string
+}
+
+// Argument is a `Role:ID` pair of a relation or an event
+type Argument struct {
+	Role string
+	ID   string
+}
+
+// Relation is an `R` annotation
+type Relation struct {
+	ID   string
+	Type string
+	Args []Argument
+}
+
+// Event is an `E` annotation, Trigger is the ID of its text-bound annotation
+type Event struct {
+	ID      string
+	Type    string
+	Trigger string
+	Args    []Argument
+}
+
+// Attribute is an `A` (or the older `M`) annotation, Value is empty for binary attributes
+type Attribute struct {
+	ID     string
+	Type   string
+	Target string
+	Value  string
+}
+
+// Normalization is an `N` annotation
+type Normalization struct {
+	ID     string
+	Type   string
+	Target string
+	Ref    string
+	Text   string
+}
+
+// Note is a `#` annotation
+type Note struct {
+	ID     string
+	Type   string
+	Target string
+	Text   string
+}
+
+// Equiv is a `*` annotation grouping equivalent text-bound annotations
+type Equiv struct {
+	Type string
+	IDs  []string
+}
+
+// Standoff holds every annotation of a .ann file, in the order they were found
+type Standoff struct {
+	TextBounds     []TextBound
+	Relations      []Relation
+	Events         []Event
+	Attributes     []Attribute
+	Normalizations []Normalization
+	Notes          []Note
+	Equivs         []Equiv
+}
+
+func (t TextBound) Begin() int {
+	return t.Spans[0].Begin
+}
+
+func (t TextBound) End() int {
+	return t.Spans[len(t.Spans)-1].End
+}
+
+// Length is the number of characters covered by the spans
+func (t TextBound) Length() int {
+	length := 0
+	for _, span := range t.Spans {
+		length += span.End - span.Begin
+	}
+	return length
+}
+
+// ParseStandoff parses every kind of annotation of a brat .ann file
+func ParseStandoff(r io.Reader) (*Standoff, error) {
+	standoff := &Standoff{}
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanLines)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		parseErr := &ParseError{Path: readerName(r), Line: lineNo, Column: 1, Raw: line, ID: annotationID(line)}
+		if len(fields) < 2 {
+			parseErr.Kind = ErrParseBadFormatTab
+			return standoff, parseErr
+		}
+		parseErr.Column = len(fields[0]) + 2
+		id := fields[0]
+		body := strings.Fields(fields[1])
+		text := strings.Join(fields[2:], "\t")
+		if len(body) == 0 {
+			parseErr.Kind = ErrParseBadFormat
+			parseErr.Err = fmt.Errorf("empty annotation")
+			return standoff, parseErr
+		}
+
+		if id == "" {
+			parseErr.Column = 1
+			parseErr.Kind = ErrParseBadFormat
+			parseErr.Err = fmt.Errorf("missing annotation ID")
+			return standoff, parseErr
+		}
+
+		switch id[0] {
+		case 'T':
+			if len(fields) < 3 {
+				parseErr.Kind = ErrParseBadFormatTab
+				return standoff, parseErr
+			}
+			tb, err := parseTextBound(id, fields[1], text)
+			if err != nil {
+				parseErr.Kind = ErrParseBadOffset
+				parseErr.Err = err
+				return standoff, parseErr
+			}
+			standoff.TextBounds = append(standoff.TextBounds, tb)
+		case 'R':
+			args, err := parseArguments(body[1:])
+			if err != nil || len(args) < 2 {
+				parseErr.Kind = ErrParseBadFormat
+				parseErr.Err = fmt.Errorf("expected \"<type> <role>:<id> <role>:<id>\" received %q", fields[1])
+				return standoff, parseErr
+			}
+			standoff.Relations = append(standoff.Relations, Relation{id, body[0], args})
+		case 'E':
+			typeAndTrigger := strings.SplitN(body[0], ":", 2)
+			args, err := parseArguments(body[1:])
+			if err != nil || len(typeAndTrigger) != 2 {
+				parseErr.Kind = ErrParseBadFormat
+				parseErr.Err = fmt.Errorf("expected \"<type>:<trigger> <role>:<id>...\" received %q", fields[1])
+				return standoff, parseErr
+			}
+			standoff.Events = append(standoff.Events, Event{id, typeAndTrigger[0], typeAndTrigger[1], args})
+		case 'A', 'M':
+			if len(body) < 2 || len(body) > 3 {
+				parseErr.Kind = ErrParseBadFormat
+				parseErr.Err = fmt.Errorf("expected \"<type> <id> [<value>]\" received %q", fields[1])
+				return standoff, parseErr
+			}
+			attribute := Attribute{ID: id, Type: body[0], Target: body[1]}
+			if len(body) == 3 {
+				attribute.Value = body[2]
+			}
+			standoff.Attributes = append(standoff.Attributes, attribute)
+		case 'N':
+			if len(body) != 3 {
+				parseErr.Kind = ErrParseBadFormat
+				parseErr.Err = fmt.Errorf("expected \"<type> <id> <ref>\" received %q", fields[1])
+				return standoff, parseErr
+			}
+			standoff.Normalizations = append(standoff.Normalizations, Normalization{id, body[0], body[1], body[2], text})
+		case '#':
+			if len(body) != 2 {
+				parseErr.Kind = ErrParseBadFormat
+				parseErr.Err = fmt.Errorf("expected \"<type> <id>\" received %q", fields[1])
+				return standoff, parseErr
+			}
+			standoff.Notes = append(standoff.Notes, Note{id, body[0], body[1], text})
+		case '*':
+			standoff.Equivs = append(standoff.Equivs, Equiv{body[0], body[1:]})
+		default:
+			parseErr.Column = 1
+			parseErr.Kind = ErrParseUnknownAnnotation
+			return standoff, parseErr
+		}
+	}
+
+	return standoff, scanner.Err()
+}
+
+func parseTextBound(id, field, text string) (TextBound, error) {
+	typeAndSpans := strings.SplitN(field, " ", 2)
+	if len(typeAndSpans) != 2 {
+		return TextBound{}, badFormatDetail(field)
+	}
+
+	tb := TextBound{ID: id, Type: typeAndSpans[0], Text: text}
+	for _, spanField := range strings.Split(typeAndSpans[1], ";") {
+		offsets := strings.Fields(spanField)
+		if len(offsets) != 2 {
+			return TextBound{}, badFormatDetail(field)
+		}
+		b, err := strconv.Atoi(offsets[0])
+		if err != nil {
+			return TextBound{}, err
+		}
+		e, err := strconv.Atoi(offsets[1])
+		if err != nil {
+			return TextBound{}, err
+		}
+		tb.Spans = append(tb.Spans, Span{b, e})
+	}
+	return tb, nil
+}
+
+func parseArguments(fields []string) ([]Argument, error) {
+	args := []Argument{}
+	for _, field := range fields {
+		roleAndID := strings.SplitN(field, ":", 2)
+		if len(roleAndID) != 2 || roleAndID[0] == "" || roleAndID[1] == "" {
+			return nil, badFormatDetail(field)
+		}
+		args = append(args, Argument{roleAndID[0], roleAndID[1]})
+	}
+	return args, nil
+}
+
+// Line formats the annotation the way it is written in a .ann file
+func (t TextBound) Line() string {
+	spans := []string{}
+	for _, span := range t.Spans {
+		spans = append(spans, fmt.Sprintf("%d %d", span.Begin, span.End))
+	}
+	return fmt.Sprintf("%s\t%s %s\t%s", t.ID, t.Type, strings.Join(spans, ";"), t.Text)
+}
+
+func formatArguments(args []Argument) string {
+	formatted := []string{}
+	for _, arg := range args {
+		formatted = append(formatted, arg.Role+":"+arg.ID)
+	}
+	return strings.Join(formatted, " ")
+}
+
+func (r Relation) Line() string {
+	return fmt.Sprintf("%s\t%s %s\t", r.ID, r.Type, formatArguments(r.Args))
+}
+
+func (e Event) Line() string {
+	if len(e.Args) == 0 {
+		return fmt.Sprintf("%s\t%s:%s", e.ID, e.Type, e.Trigger)
+	}
+	return fmt.Sprintf("%s\t%s:%s %s", e.ID, e.Type, e.Trigger, formatArguments(e.Args))
+}
+
+func (a Attribute) Line() string {
+	if a.Value == "" {
+		return fmt.Sprintf("%s\t%s %s", a.ID, a.Type, a.Target)
+	}
+	return fmt.Sprintf("%s\t%s %s %s", a.ID, a.Type, a.Target, a.Value)
+}
+
+func (n Normalization) Line() string {
+	return fmt.Sprintf("%s\t%s %s %s\t%s", n.ID, n.Type, n.Target, n.Ref, n.Text)
+}
+
+func (n Note) Line() string {
+	return fmt.Sprintf("%s\t%s %s\t%s", n.ID, n.Type, n.Target, n.Text)
+}
+
+func (e Equiv) Line() string {
+	return fmt.Sprintf("*\t%s %s", e.Type, strings.Join(e.IDs, " "))
+}
+
+// String formats the annotations as a .ann file
+func (s *Standoff) String() string {
+	lines := []string{}
+	for _, t := range s.TextBounds {
+		lines = append(lines, t.Line())
+	}
+	for _, r := range s.Relations {
+		lines = append(lines, r.Line())
+	}
+	for _, e := range s.Events {
+		lines = append(lines, e.Line())
+	}
+	for _, a := range s.Attributes {
+		lines = append(lines, a.Line())
+	}
+	for _, n := range s.Normalizations {
+		lines = append(lines, n.Line())
+	}
+	for _, n := range s.Notes {
+		lines = append(lines, n.Line())
+	}
+	for _, e := range s.Equivs {
+		lines = append(lines, e.Line())
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
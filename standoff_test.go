@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StandoffSuite struct {
+	suite.Suite
+}
+
+func (suite *StandoffSuite) TestParseStandoff() {
+	annFile, err := os.Open("./testData/news/000-introduction.ann")
+	suite.Nil(err)
+	defer annFile.Close()
+
+	standoff, err := ParseStandoff(annFile)
+	suite.Nil(err)
+	suite.Equal(7, len(standoff.TextBounds))
+	suite.Equal(TextBound{"T2", "Money", []Span{{456, 468}}, "$100 million"}, standoff.TextBounds[1])
+	suite.Equal([]Relation{
+		{"R2", "Origin", []Argument{{"Arg1", "T6"}, {"Arg2", "T7"}}},
+		{"R1", "Family", []Argument{{"Arg1", "T4"}, {"Arg2", "T5"}}},
+	}, standoff.Relations)
+	suite.Equal([]Event{{"E1", "Transfer-money", "T3", []Argument{{"Giver-Arg", "T1"}, {"Money-Arg", "T2"}, {"Beneficiary-Arg", "T4"}, {"Recipient-Arg", "T6"}}}}, standoff.Events)
+	suite.Equal(5, len(standoff.Attributes))
+	suite.Equal(Attribute{"A1", "Mention", "T4", "Name"}, standoff.Attributes[0])
+	suite.Equal(Attribute{"A2", "Individual", "T4", ""}, standoff.Attributes[1])
+	suite.Equal([]Normalization{{"N1", "Reference", "T5", "Wikipedia:64488", "Carlos Salinas de Gortari"}}, standoff.Normalizations)
+	suite.Equal([]Note{{"#1", "AnnotatorNotes", "T2", "100000000 USD"}}, standoff.Notes)
+
+	// Formatting and parsing again gives the same annotations
+	reparsed, err := ParseStandoff(strings.NewReader(standoff.String()))
+	suite.Nil(err)
+	suite.Equal(standoff, reparsed)
+}
+
+func (suite *StandoffSuite) TestParseStandoffDiscontinuous() {
+	annFile, err := os.Open("./testData/invalid-files/dicontinous-text-bound-annotations/030-login.ann")
+	suite.Nil(err)
+	defer annFile.Close()
+
+	standoff, err := ParseStandoff(annFile)
+	suite.Nil(err)
+	suite.Equal([]Span{{0, 5}, {16, 23}}, standoff.TextBounds[0].Spans)
+	suite.Equal(12, standoff.TextBounds[0].Length())
+	suite.Equal(23, standoff.TextBounds[0].End())
+	suite.Equal("T1\tLocation 0 5;16 23\tNorth America", standoff.TextBounds[0].Line())
+}
+
+func (suite *StandoffSuite) TestParseStandoffInvalid() {
+	for ann, kind := range map[string]error{
+		"T1\tOrganization 77 INVALID\tYouTube": ErrParseBadOffset,
+		"T1\tOrganization 281 288":             ErrParseBadFormatTab,
+		"T1 Organization 281 288 YouTube":      ErrParseBadFormatTab,
+		"R1\tOrigin Arg1:T6\t":                 ErrParseBadFormat,
+		"E1\tMerge-org Org-Arg:T2":             ErrParseBadFormat,
+		"A1\tConfidence":                       ErrParseBadFormat,
+		"X1\tSomething T1":                     ErrParseUnknownAnnotation,
+		"\tPerson 0 4\tSony":                   ErrParseBadFormat,
+	} {
+		_, err := ParseStandoff(strings.NewReader("T9\tPerson 0 4\tSony\n" + ann))
+		suite.True(errors.Is(err, kind), ann)
+
+		var parseErr *ParseError
+		suite.True(errors.As(err, &parseErr))
+		suite.Equal(2, parseErr.Line)
+	}
+}
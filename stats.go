@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	ErrStatsUnknownFormat = "unknown stats format: %s, expected `table` or `json`"
+	ErrValidateNoInput    = "no input specified, use `--folderPath` or `--include`"
+)
+
+// spanLengthBuckets are the upper bounds (inclusive) of the span length histogram, the last bucket is open ended
+var spanLengthBuckets = []int{5, 10, 20, 50}
+
+type HistogramBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type SpanLengthStats struct {
+	Min       int               `json:"min"`
+	Max       int               `json:"max"`
+	Mean      float64           `json:"mean"`
+	Median    float64           `json:"median"`
+	Histogram []HistogramBucket `json:"histogram"`
+}
+
+type DocumentStats struct {
+	Document   string         `json:"document"`
+	Entities   map[string]int `json:"entities"`
+	Dropped    int            `json:"dropped"`
+	Relations  int            `json:"relations"`
+	Events     int            `json:"events"`
+	Attributes int            `json:"attributes"`
+}
+
+// CollectionStats is the summary printed by the `stats` command. Only the text-bound annotations whose
+// type is in `[entities]` are counted as entities, the others are counted as dropped.
+type CollectionStats struct {
+	Documents      int             `json:"documents"`
+	Entities       map[string]int  `json:"entities"`
+	Dropped        map[string]int  `json:"dropped"`
+	DroppedTotal   int             `json:"dropped_total"`
+	SpanLengths    SpanLengthStats `json:"span_lengths"`
+	Overlapping    int             `json:"overlapping"`
+	Nested         int             `json:"nested"`
+	Relations      map[string]int  `json:"relations"`
+	Events         map[string]int  `json:"events"`
+	Attributes     map[string]int  `json:"attributes"`
+	Normalizations int             `json:"normalizations"`
+	Notes          int             `json:"notes"`
+	PerDocument    []DocumentStats `json:"per_document"`
+}
+
+// ComputeStats parses every document of the collection and summarizes its annotations
+func ComputeStats(collection *Collection) (*CollectionStats, error) {
+	stats := &CollectionStats{
+		Documents:   len(collection.Ann),
+		Entities:    make(map[string]int),
+		Dropped:     make(map[string]int),
+		Relations:   make(map[string]int),
+		Events:      make(map[string]int),
+		Attributes:  make(map[string]int),
+		PerDocument: []DocumentStats{},
+	}
+	lengths := []int{}
+
+	for _, annPath := range collection.Ann {
+		_, entities, err := collection.DocumentTypes(annPath, false)
+		if err != nil {
+			return nil, err
+		}
+		standoff, err := collection.ReadStandoff(annPath)
+		if err != nil {
+			return nil, err
+		}
+
+		docStats := DocumentStats{
+			Document:   annPath,
+			Entities:   make(map[string]int),
+			Relations:  len(standoff.Relations),
+			Events:     len(standoff.Events),
+			Attributes: len(standoff.Attributes),
+		}
+		kept := []TextBound{}
+		for _, tb := range standoff.TextBounds {
+			if entities != nil && !entities[tb.Type] {
+				stats.Dropped[tb.Type]++
+				stats.DroppedTotal++
+				docStats.Dropped++
+				continue
+			}
+			kept = append(kept, tb)
+			stats.Entities[tb.Type]++
+			docStats.Entities[tb.Type]++
+			lengths = append(lengths, tb.Length())
+		}
+
+		overlapping, nested := CountOverlaps(kept)
+		stats.Overlapping += overlapping
+		stats.Nested += nested
+
+		for _, r := range standoff.Relations {
+			stats.Relations[r.Type]++
+		}
+		for _, e := range standoff.Events {
+			stats.Events[e.Type]++
+		}
+		for _, a := range standoff.Attributes {
+			stats.Attributes[a.Type]++
+		}
+		stats.Normalizations += len(standoff.Normalizations)
+		stats.Notes += len(standoff.Notes)
+		stats.PerDocument = append(stats.PerDocument, docStats)
+	}
+
+	stats.SpanLengths = NewSpanLengthStats(lengths)
+	return stats, nil
+}
+
+// ReadStandoff parses every annotation of the .ann file at annPath
+func (c *Collection) ReadStandoff(annPath string) (*Standoff, error) {
+	annFile, err := c.Open(annPath)
+	if err != nil {
+		return nil, err
+	}
+	defer annFile.Close()
+	return ParseStandoff(annFile)
+}
+
+// ValidateStandoff checks every annotation of the .ann file with `--strict`: the file has to parse as brat
+// standoff and the text of every text-bound annotation has to be the text of its spans, joined by a space
+func (c *Collection) ValidateStandoff(annPath, text string) error {
+	data, err := c.ReadFile(annPath)
+	if err != nil {
+		return err
+	}
+	standoff, err := ParseStandoff(namedReader{bytes.NewReader(data), annPath})
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, tb := range standoff.TextBounds {
+		fragments := []string{}
+		for _, span := range tb.Spans {
+			fragment, err := GetSubString(text, span.Begin, span.End)
+			if err != nil {
+				return &ParseError{Path: annPath, ID: tb.ID, Kind: ErrParseBadSpan, Err: err}
+			}
+			fragments = append(fragments, fragment)
+		}
+		if spanText := strings.Join(fragments, " "); spanText != tb.Text {
+			parseErr := &ParseError{Path: annPath, ID: tb.ID, Kind: ErrParseTextMismatch,
+				Err: fmt.Errorf("%q in the .ann, %q in the .txt", tb.Text, spanText)}
+			for i, line := range lines {
+				if annotationID(line) == tb.ID {
+					parseErr.Line, parseErr.Raw = i+1, strings.TrimSuffix(line, "\r")
+					break
+				}
+			}
+			return parseErr
+		}
+	}
+	return nil
+}
+
+// CountOverlaps counts the pairs of text-bound annotations that overlap without one containing the other,
+// and the pairs where one is nested in the other
+func CountOverlaps(textBounds []TextBound) (int, int) {
+	sorted := append([]TextBound{}, textBounds...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Begin() < sorted[j].Begin()
+	})
+
+	overlapping, nested := 0, 0
+	for i := range sorted {
+		for j := i + 1; j < len(sorted) && sorted[j].Begin() < sorted[i].End(); j++ {
+			if sorted[j].End() <= sorted[i].End() || sorted[j].Begin() == sorted[i].Begin() {
+				nested++
+			} else {
+				overlapping++
+			}
+		}
+	}
+	return overlapping, nested
+}
+
+func NewSpanLengthStats(lengths []int) SpanLengthStats {
+	stats := SpanLengthStats{Histogram: []HistogramBucket{}}
+	lower := 1
+	for _, upper := range spanLengthBuckets {
+		stats.Histogram = append(stats.Histogram, HistogramBucket{Label: fmt.Sprintf("%d-%d", lower, upper)})
+		lower = upper + 1
+	}
+	stats.Histogram = append(stats.Histogram, HistogramBucket{Label: fmt.Sprintf("%d+", lower)})
+
+	if len(lengths) == 0 {
+		return stats
+	}
+
+	sorted := append([]int{}, lengths...)
+	sort.Ints(sorted)
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+
+	total := 0
+	for _, length := range sorted {
+		total += length
+		bucket := len(spanLengthBuckets)
+		for i, upper := range spanLengthBuckets {
+			if length <= upper {
+				bucket = i
+				break
+			}
+		}
+		stats.Histogram[bucket].Count++
+	}
+	stats.Mean = float64(total) / float64(len(sorted))
+	if len(sorted)%2 == 1 {
+		stats.Median = float64(sorted[len(sorted)/2])
+	} else {
+		stats.Median = float64(sorted[len(sorted)/2-1]+sorted[len(sorted)/2]) / 2
+	}
+	return stats
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteTable prints the statistics as human readable tables
+func (s *CollectionStats) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	writeCounts := func(title string, counts map[string]int) {
+		fmt.Fprintf(tw, "\n%s\n", title)
+		if len(counts) == 0 {
+			fmt.Fprintln(tw, "  -")
+		}
+		for _, key := range sortedKeys(counts) {
+			fmt.Fprintf(tw, "  %s\t%d\n", key, counts[key])
+		}
+	}
+
+	fmt.Fprintf(tw, "Documents\t%d\n", s.Documents)
+	writeCounts("Entities", s.Entities)
+	writeCounts(fmt.Sprintf("Dropped (type not in [entities]): %d", s.DroppedTotal), s.Dropped)
+
+	fmt.Fprintf(tw, "\nSpan lengths\n")
+	fmt.Fprintf(tw, "  min\t%d\n  max\t%d\n  mean\t%.2f\n  median\t%.1f\n", s.SpanLengths.Min, s.SpanLengths.Max, s.SpanLengths.Mean, s.SpanLengths.Median)
+	for _, bucket := range s.SpanLengths.Histogram {
+		fmt.Fprintf(tw, "  %s\t%d\n", bucket.Label, bucket.Count)
+	}
+	fmt.Fprintf(tw, "\nOverlapping spans\t%d\nNested spans\t%d\n", s.Overlapping, s.Nested)
+
+	writeCounts("Relations", s.Relations)
+	writeCounts("Events", s.Events)
+	writeCounts("Attributes", s.Attributes)
+	fmt.Fprintf(tw, "\nNormalizations\t%d\nNotes\t%d\n", s.Normalizations, s.Notes)
+
+	fmt.Fprintf(tw, "\nDocument\tEntities\tDropped\tRelations\tEvents\tAttributes\n")
+	for _, doc := range s.PerDocument {
+		entities := 0
+		for _, count := range doc.Entities {
+			entities += count
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", doc.Document, entities, doc.Dropped, doc.Relations, doc.Events, doc.Attributes)
+	}
+	return tw.Flush()
+}
+
+// runStats implements the `stats` command
+func runStats(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	folderPath := flags.StringP("folderPath", "p", "", "Path to the folder (or archive) containing the collection")
+	include := flags.StringArrayP("include", "i", []string{}, "Glob pattern of the annotation files (.ann) to include. Can be repeated")
+	exclude := flags.StringArrayP("exclude", "x", []string{}, "Glob pattern of the annotation files (.ann) to skip. Can be repeated")
+	confFile := flags.StringP("conf", "c", "", "Location of the annotation configuration file (annotation.conf) when `--folderPath` is not used")
+	format := flags.String("format", "table", "Output format: table or json")
+	oFileName := flags.StringP("output", "o", "", "Name of the output file to be generated")
+	overWrite := flags.BoolP("force", "f", false, "If you wish to overwrite the generated file then set force to true")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := Options{FolderPath: *folderPath, Include: *include, Exclude: *exclude, ConfFile: *confFile, OutputFile: *oFileName, OverWrite: *overWrite}
+	if opts.FolderPath == "" && len(opts.Include) == 0 {
+		return fmt.Errorf(ErrValidateNoInput)
+	}
+
+	collection, err := OpenCollection(opts)
+	if err != nil {
+		return err
+	}
+	stats, err := ComputeStats(collection)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	switch *format {
+	case "table":
+		err = stats.WriteTable(buf)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(stats, "", "  ")
+		buf.Write(append(data, '\n'))
+	default:
+		err = fmt.Errorf(ErrStatsUnknownFormat, *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(opts.OutputFile, buf.String(), defaultOutputMode(opts.OverWrite))
+}
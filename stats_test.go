@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatsSuite struct {
+	suite.Suite
+}
+
+func (suite *StatsSuite) TestComputeStats() {
+	collection, err := OpenCollection(Options{FolderPath: "./testData/news"})
+	suite.Nil(err)
+
+	stats, err := ComputeStats(collection)
+	suite.Nil(err)
+	suite.Equal(13, stats.Documents)
+	suite.Equal(map[string]int{"GPE": 12, "Money": 1, "Organization": 56, "Person": 27}, stats.Entities)
+	suite.Equal(map[string]int{"Merge-org": 14, "Report": 8, "Transfer-money": 1}, stats.Dropped)
+	suite.Equal(23, stats.DroppedTotal)
+	suite.Equal(map[string]int{"Employment": 1, "Family": 3, "Located": 3, "Origin": 1}, stats.Relations)
+	suite.Equal(23, stats.Events["Merge-org"]+stats.Events["Report"]+stats.Events["Transfer-money"])
+	suite.Equal(6, stats.Attributes["Confidence"])
+	suite.Equal(1, stats.Normalizations)
+	suite.Equal(4, stats.Notes)
+	suite.Equal(3, stats.SpanLengths.Min)
+	suite.Equal(24, stats.SpanLengths.Max)
+	suite.Equal("testData/news/000-introduction.ann", stats.PerDocument[0].Document)
+	suite.Equal(1, stats.PerDocument[0].Dropped)
+
+	buf := &bytes.Buffer{}
+	suite.Nil(stats.WriteTable(buf))
+	suite.Contains(buf.String(), "Dropped (type not in [entities]): 23")
+}
+
+func (suite *StatsSuite) TestComputeStatsWithoutConf() {
+	dir, err := ioutil.TempDir("", "stats")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"000-introduction.ann", "000-introduction.txt"} {
+		data, err := ioutil.ReadFile(filepath.Join("./testData/news", name))
+		suite.Nil(err)
+		suite.Nil(ioutil.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+
+	// Every type is counted when no annotation.conf applies
+	collection, err := OpenCollection(Options{FolderPath: dir})
+	suite.Nil(err)
+	stats, err := ComputeStats(collection)
+	suite.Nil(err)
+	suite.Empty(stats.Dropped)
+	suite.Equal(1, stats.Entities["Transfer-money"])
+}
+
+func (suite *StatsSuite) TestCountOverlaps() {
+	overlapping, nested := CountOverlaps([]TextBound{
+		{ID: "T1", Spans: []Span{{0, 10}}},
+		{ID: "T2", Spans: []Span{{2, 5}}},
+		{ID: "T3", Spans: []Span{{8, 15}}},
+		{ID: "T4", Spans: []Span{{20, 25}}},
+		{ID: "T5", Spans: []Span{{20, 25}}},
+	})
+	suite.Equal(1, overlapping)
+	suite.Equal(2, nested)
+}
+
+func (suite *StatsSuite) TestSpanLengthStats() {
+	stats := NewSpanLengthStats([]int{1, 4, 6, 60})
+	suite.Equal(1, stats.Min)
+	suite.Equal(60, stats.Max)
+	suite.Equal(17.75, stats.Mean)
+	suite.Equal(5.0, stats.Median)
+	suite.Equal([]HistogramBucket{{"1-5", 2}, {"6-10", 1}, {"11-20", 0}, {"21-50", 0}, {"51+", 1}}, stats.Histogram)
+
+	suite.Equal(0, NewSpanLengthStats([]int{}).Max)
+}
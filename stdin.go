@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// StdinPath can be given instead of a path to read from stdin or write to stdout
+	StdinPath = "-"
+	stdinName = "<stdin>"
+
+	ErrEnvelopeNoTxt          = "the JSON envelope read from stdin has no `txt` field"
+	ErrValidateMultipleStdin  = "only one of --ann, --txt, --conf and --files-from can be read from stdin (`-`)"
+	ErrValidateStdinMultiDocs = "only a single document can be read from stdin (`-`)"
+)
+
+// stdin is a variable so tests can feed their own input
+var stdin io.Reader = os.Stdin
+
+// stdinData holds stdin once it has been read, as it can only be read once
+var stdinData []byte
+
+func readStdin() ([]byte, error) {
+	if stdinData == nil {
+		data, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		stdinData = data
+	}
+	return stdinData, nil
+}
+
+// Envelope is a single document sent on stdin as JSON
+type Envelope struct {
+	Name string `json:"name"`
+	Txt  string `json:"txt"`
+	Ann  string `json:"ann"`
+	Conf string `json:"conf"`
+}
+
+// ReadStdinArchive reads a single document from stdin, either as a JSON Envelope or as a
+// (optionally gzipped) tar stream, and returns it as an in memory Archive
+func ReadStdinArchive() (*Archive, error) {
+	data, err := readStdin()
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		envelope := Envelope{}
+		if err := json.Unmarshal(trimmed, &envelope); err != nil {
+			return nil, err
+		}
+		return envelope.Archive()
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return ReadTar(stdinName, bufio.NewReader(gz))
+	}
+	return ReadTar(stdinName, bytes.NewReader(data))
+}
+
+func (e Envelope) Archive() (*Archive, error) {
+	if e.Txt == "" {
+		return nil, errors.New(ErrEnvelopeNoTxt)
+	}
+	name := e.Name
+	if name == "" {
+		name = "document"
+	}
+
+	archive := &Archive{Path: stdinName, Files: map[string][]byte{
+		name + ".txt": []byte(e.Txt),
+		name + ".ann": []byte(e.Ann),
+	}}
+	if e.Conf != "" {
+		archive.Files["annotation.conf"] = []byte(e.Conf)
+	}
+	return archive, nil
+}
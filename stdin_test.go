@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StdinSuite struct {
+	suite.Suite
+	TmpDir string
+	Txt    string
+	Ann    string
+	Conf   string
+}
+
+func (suite *StdinSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "stdin")
+	suite.Nil(err)
+	suite.TmpDir = dir
+
+	for path, dest := range map[string]*string{
+		"./testData/news/000-introduction.txt": &suite.Txt,
+		"./testData/news/000-introduction.ann": &suite.Ann,
+		"./testData/news/annotation.conf":      &suite.Conf,
+	} {
+		data, err := ioutil.ReadFile(path)
+		suite.Nil(err)
+		*dest = string(data)
+	}
+}
+
+func (suite *StdinSuite) TearDownTest() {
+	os.RemoveAll(suite.TmpDir)
+	stdin = os.Stdin
+	stdinData = nil
+}
+
+func (suite *StdinSuite) setStdin(data []byte) {
+	stdin = bytes.NewReader(data)
+	stdinData = nil
+}
+
+func (suite *StdinSuite) convert(opts Options) string {
+	opts.OutputFile = filepath.Join(suite.TmpDir, "out.jsonl")
+	opts.OverWrite = true
+	suite.Nil(handleMain(opts))
+
+	data, err := ioutil.ReadFile(opts.OutputFile)
+	suite.Nil(err)
+	return string(data)
+}
+
+func (suite *StdinSuite) TestStdinFlags() {
+	expected := suite.convert(Options{
+		AnnFiles: "./testData/news/000-introduction.ann",
+		TxtFiles: "./testData/news/000-introduction.txt",
+		ConfFile: "./testData/news/annotation.conf",
+	})
+
+	suite.setStdin([]byte(suite.Ann))
+	suite.Equal(expected, suite.convert(Options{
+		AnnFiles: StdinPath,
+		TxtFiles: "./testData/news/000-introduction.txt",
+		ConfFile: "./testData/news/annotation.conf",
+	}))
+
+	suite.setStdin([]byte(suite.Txt))
+	suite.Equal(expected, suite.convert(Options{
+		AnnFiles: "./testData/news/000-introduction.ann",
+		TxtFiles: StdinPath,
+		ConfFile: "./testData/news/annotation.conf",
+	}))
+}
+
+func (suite *StdinSuite) TestStdinConf() {
+	// The tools.conf and visual.conf of the working directory do not belong to a conf read from stdin
+	annPath, err := filepath.Abs("./testData/news/000-introduction.ann")
+	suite.Nil(err)
+	wd, err := os.Getwd()
+	suite.Nil(err)
+	suite.Nil(os.Chdir(suite.TmpDir))
+	defer os.Chdir(wd)
+	suite.Nil(ioutil.WriteFile(toolsConfName, []byte("[options\n"), 0600))
+	suite.Nil(ioutil.WriteFile(visualConfName, []byte("[labels\n"), 0600))
+
+	suite.setStdin([]byte(suite.Conf))
+	opts := Options{AnnFiles: annPath, ConfFile: StdinPath, Segment: SegmentSentence, Crossing: CrossingDrop, Schema: "schema.json"}
+	output := suite.convert(opts)
+	suite.Contains(output, `"source_doc"`)
+
+	collection, err := OpenCollection(opts)
+	suite.Nil(err)
+	suite.Equal("", collection.ToolsConfPath(annPath))
+	visual, err := collection.VisualConf()
+	suite.Nil(err)
+	suite.Nil(visual)
+}
+
+func (suite *StdinSuite) TestStdinEnvelope() {
+	envelope, err := json.Marshal(Envelope{Txt: suite.Txt, Ann: suite.Ann, Conf: suite.Conf})
+	suite.Nil(err)
+	suite.setStdin(envelope)
+	output := suite.convert(Options{FolderPath: StdinPath})
+	suite.Contains(output, `[549,560,"GPE"]],"Meta":{"conf":"annotation.conf"}}`)
+
+	// Without a conf in the envelope `--conf` is used
+	envelope, err = json.Marshal(Envelope{Txt: suite.Txt, Ann: suite.Ann})
+	suite.Nil(err)
+	suite.setStdin(envelope)
+	suite.Equal(output, suite.convert(Options{FolderPath: StdinPath, ConfFile: "./testData/news/annotation.conf"}))
+
+	suite.setStdin([]byte(`{"ann": "T1	Person 0 4	Sony"}`))
+	suite.NotNil(handleMain(Options{FolderPath: StdinPath}))
+}
+
+func (suite *StdinSuite) TestStdinTar() {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, data := range map[string]string{"doc.txt": suite.Txt, "doc.ann": suite.Ann, "annotation.conf": suite.Conf} {
+		suite.Nil(tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(data))
+		suite.Nil(err)
+	}
+	suite.Nil(tw.Close())
+
+	suite.setStdin(buf.Bytes())
+	output := suite.convert(Options{FolderPath: StdinPath})
+	suite.Equal(1, strings.Count(output, "\n"))
+	suite.Contains(output, `[418,426,"Organization"]`)
+}
+
+func (suite *StdinSuite) TestValidateStdin() {
+	suite.Nil(ValidateStdin("-", "a.txt", "annotation.conf", ""))
+	suite.Nil(ValidateStdin("a.ann,b.ann", "a.txt,b.txt", "-", ""))
+	suite.Nil(ValidateStdin("", "", "annotation.conf", "-"))
+	suite.NotNil(ValidateStdin("-", "-", "annotation.conf", ""))
+	suite.NotNil(ValidateStdin("a.ann", "-", "-", ""))
+	suite.NotNil(ValidateStdin("-,b.ann", "a.txt,b.txt", "annotation.conf", ""))
+	suite.NotNil(ValidateStdin("-", "a.txt", "annotation.conf", "list.txt"))
+	suite.Nil(ValidateFlags(Options{AnnFiles: "-", TxtFiles: "a.txt", ConfFile: "annotation.conf"}))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const (
+	toolsConfName = "tools.conf"
+
+	TokenizerWhitespace = "whitespace"
+	TokenizerPTBLike    = "ptblike"
+	SplitterRegex       = "regex"
+	SplitterNewline     = "newline"
+
+	ErrUnsupportedTokenizer = "unsupported tokenizer: %s, expected `whitespace` or `ptblike`"
+	ErrUnsupportedSplitter  = "unsupported sentence splitter: %s, expected `regex` or `newline`"
+)
+
+// ptbContractions are split from the word they end, the way the Penn Treebank tokenizes them
+var ptbContractions = []string{"n't", "'s", "'re", "'ve", "'ll", "'d", "'m"}
+
+// ToolsConf holds the `[options]` of a brat `tools.conf` used by the converter
+type ToolsConf struct {
+	Tokenizer string
+	Splitter  string
+}
+
+func ParseToolsConf(r io.Reader) (*ToolsConf, error) {
+	sections, err := ReadConfSections(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := &ToolsConf{}
+	for _, line := range sections["options"] {
+		name, options := confOptions(line)
+		switch name {
+		case "Tokens":
+			tools.Tokenizer = options["tokenizer"]
+		case "Sentences":
+			tools.Splitter = options["splitter"]
+		}
+	}
+	return tools, nil
+}
+
+// ToolsConf reads the `tools.conf` closest to the document at annPath, the way brat looks up its confs, or the one
+// in the root of the collection when annPath is empty. It is empty when there is none.
+func (c *Collection) ToolsConf(annPath string) (*ToolsConf, error) {
+	toolsPath := c.ToolsConfPath(annPath)
+	if toolsPath == "" {
+		return &ToolsConf{}, nil
+	}
+	if tools, ok := c.confTools[toolsPath]; ok {
+		return tools, nil
+	}
+	f, err := c.Open(toolsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tools, err := ParseToolsConf(f)
+	if err != nil {
+		return nil, err
+	}
+	c.confTools[toolsPath] = tools
+	return tools, nil
+}
+
+// ToolsConfPath returns the path of the `tools.conf` used by the document at annPath, "" when there is none
+func (c *Collection) ToolsConfPath(annPath string) string {
+	if c.NearestFile == nil {
+		return ""
+	}
+	return c.NearestFile(annPath, toolsConfName)
+}
+
+// Resolve returns the tokenizer and splitter to use: the flags first, then tools.conf, then the defaults
+func (t *ToolsConf) Resolve(tokenizer, splitter string) (string, string) {
+	switch {
+	case tokenizer != "":
+	case t.Tokenizer != "":
+		tokenizer = t.Tokenizer
+	default:
+		tokenizer = TokenizerWhitespace
+	}
+	switch {
+	case splitter != "":
+	case t.Splitter != "":
+		splitter = t.Splitter
+	default:
+		splitter = SplitterRegex
+	}
+	return tokenizer, splitter
+}
+
+func ValidateTokenizer(tokenizer string) error {
+	if tokenizer != TokenizerWhitespace && tokenizer != TokenizerPTBLike {
+		return fmt.Errorf(ErrUnsupportedTokenizer, tokenizer)
+	}
+	return nil
+}
+
+func ValidateSplitter(splitter string) error {
+	if splitter != SplitterRegex && splitter != SplitterNewline {
+		return fmt.Errorf(ErrUnsupportedSplitter, splitter)
+	}
+	return nil
+}
+
+// Tokenize returns the character spans of the tokens of the text. whitespace splits on spaces only,
+// ptblike also splits the punctuation and the contractions from the words.
+func Tokenize(text, tokenizer string) []Span {
+	tokens := TokenSpans(text)
+	if tokenizer != TokenizerPTBLike {
+		return tokens
+	}
+
+	chars := textChars(text)
+	ptbTokens := []Span{}
+	for _, token := range tokens {
+		ptbTokens = append(ptbTokens, splitPTBToken(chars, token)...)
+	}
+	return ptbTokens
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// splitPTBToken splits a whitespace separated token in words, numbers such as 1,000.5, contractions
+// and single punctuation characters
+func splitPTBToken(chars []rune, token Span) []Span {
+	spans := []Span{}
+	for i := token.Begin; i < token.End; {
+		if !isWordChar(chars[i]) {
+			spans = append(spans, Span{i, i + 1})
+			i++
+			continue
+		}
+
+		j := i
+		for j < token.End {
+			if isWordChar(chars[j]) {
+				j++
+				continue
+			}
+			// Decimal and thousands separators stay in the number
+			if (chars[j] == '.' || chars[j] == ',') && unicode.IsDigit(chars[j-1]) && j+1 < token.End && unicode.IsDigit(chars[j+1]) {
+				j++
+				continue
+			}
+			break
+		}
+
+		word := Span{i, j}
+		// A contraction is only split when the whole word ends with it, as in "don't" or "John's"
+		if j < token.End && chars[j] == '\'' {
+			k := j + 1
+			for k < token.End && isWordChar(chars[k]) {
+				k++
+			}
+			word.End = k
+		}
+		spans = append(spans, splitContraction(chars, word)...)
+		i = word.End
+	}
+	return spans
+}
+
+func splitContraction(chars []rune, word Span) []Span {
+	lower := strings.ToLower(string(chars[word.Begin:word.End]))
+	for _, contraction := range ptbContractions {
+		length := len([]rune(contraction))
+		if strings.HasSuffix(lower, contraction) && word.End-word.Begin > length {
+			return []Span{{word.Begin, word.End - length}, {word.End - length, word.End}}
+		}
+	}
+	if strings.Contains(lower, "'") {
+		// An unknown contraction is split at the apostrophe
+		apostrophe := word.Begin + len([]rune(lower[:strings.Index(lower, "'")]))
+		spans := []Span{}
+		if apostrophe > word.Begin {
+			spans = append(spans, Span{word.Begin, apostrophe})
+		}
+		spans = append(spans, Span{apostrophe, apostrophe + 1})
+		if apostrophe+1 < word.End {
+			spans = append(spans, Span{apostrophe + 1, word.End})
+		}
+		return spans
+	}
+	return []Span{word}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ToolsSuite struct {
+	suite.Suite
+}
+
+func (suite *ToolsSuite) TestParseToolsConf() {
+	f, err := os.Open("./testData/news/tools.conf")
+	suite.Nil(err)
+	defer f.Close()
+
+	tools, err := ParseToolsConf(f)
+	suite.Nil(err)
+	suite.Equal(&ToolsConf{Tokenizer: TokenizerWhitespace, Splitter: SplitterNewline}, tools)
+
+	tools, err = ParseToolsConf(strings.NewReader("[options]\nValidation\tvalidate:none\n"))
+	suite.Nil(err)
+	suite.Equal(&ToolsConf{}, tools)
+}
+
+func (suite *ToolsSuite) TestCollectionToolsConf() {
+	collection, err := OpenCollection(Options{FolderPath: "./testData/news"})
+	suite.Nil(err)
+	tools, err := collection.ToolsConf("")
+	suite.Nil(err)
+	suite.Equal(SplitterNewline, tools.Splitter)
+
+	collection, err = OpenCollection(Options{FolderPath: "./testData/nested-conf"})
+	suite.Nil(err)
+	tools, err = collection.ToolsConf("")
+	suite.Nil(err)
+	suite.Equal(&ToolsConf{}, tools)
+
+	// Every document uses the tools.conf closest to it
+	collection, err = OpenCollection(Options{FolderPath: "./testData/CoNLL-ST_2002"})
+	suite.Nil(err)
+	for _, doc := range []struct{ annPath, splitter string }{
+		{"testData/CoNLL-ST_2002/esp/esp.train-doc-100.ann", SplitterNewline},
+		{"testData/CoNLL-ST_2002/ned/ned.train-doc-118.ann", SplitterRegex},
+	} {
+		tools, err = collection.ToolsConf(doc.annPath)
+		suite.Nil(err)
+		suite.Equal(doc.splitter, tools.Splitter, doc.annPath)
+	}
+}
+
+func (suite *ToolsSuite) TestDocumentTools() {
+	dir, err := ioutil.TempDir("", "tools")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	// The Spanish documents are split on newlines, the Dutch ones by the regex splitter
+	output := filepath.Join(dir, "out.jsonl")
+	opts := Options{FolderPath: "./testData/CoNLL-ST_2002", Include: []string{"esp/esp.train-doc-100.ann", "ned/ned.train-doc-118.ann"},
+		OutputFile: output, Segment: SegmentSentence, Crossing: CrossingDrop}
+	suite.Nil(handleMain(opts))
+	f, err := os.Open(output)
+	suite.Nil(err)
+	defer f.Close()
+	records, err := ReadAcharya(f)
+	suite.Nil(err)
+
+	segments := make(map[string]int)
+	for _, record := range records {
+		segments[filepath.ToSlash(record.Meta.SourceDoc)]++
+	}
+	for txtPath, splitter := range map[string]string{
+		"testData/CoNLL-ST_2002/esp/esp.train-doc-100.txt": SplitterNewline,
+		"testData/CoNLL-ST_2002/ned/ned.train-doc-118.txt": SplitterRegex,
+	} {
+		text, err := ioutil.ReadFile(txtPath)
+		suite.Nil(err)
+		suite.Equal(len(SegmentText(string(text), SegmentSentence, splitter)), segments[txtPath], txtPath)
+	}
+}
+
+func (suite *ToolsSuite) TestResolve() {
+	tools := &ToolsConf{Splitter: SplitterNewline}
+	tokenizer, splitter := tools.Resolve("", "")
+	suite.Equal(TokenizerWhitespace, tokenizer)
+	suite.Equal(SplitterNewline, splitter)
+
+	tokenizer, splitter = tools.Resolve(TokenizerPTBLike, SplitterRegex)
+	suite.Equal(TokenizerPTBLike, tokenizer)
+	suite.Equal(SplitterRegex, splitter)
+}
+
+func (suite *ToolsSuite) TestTokenize() {
+	text := "I don't owe John's \"$1,000.50\"."
+	suite.Equal([]Span{{0, 1}, {2, 7}, {8, 11}, {12, 18}, {19, 31}}, Tokenize(text, TokenizerWhitespace))
+	suite.Equal([]Span{{0, 1}, {2, 4}, {4, 7}, {8, 11}, {12, 16}, {16, 18}, {19, 20}, {20, 21}, {21, 29}, {29, 30}, {30, 31}},
+		Tokenize(text, TokenizerPTBLike))
+	suite.Equal([]Span{{0, 1}, {1, 2}, {2, 7}}, Tokenize("O'Brien", TokenizerPTBLike))
+}
+
+func (suite *ToolsSuite) TestNewlineSplitter() {
+	text := "First line. Still first\r\nSecond line\n\n Third"
+	suite.Equal([]Span{{0, 23}, {24, 35}, {38, 43}}, SegmentText(text, SegmentSentence, SplitterNewline))
+	suite.Equal([]Span{{0, 11}, {12, 35}, {38, 43}}, SegmentText(text, SegmentSentence, SplitterRegex))
+}
+
+func (suite *ToolsSuite) TestValidateTools() {
+	suite.Nil(ValidateTokenizer(TokenizerPTBLike))
+	suite.EqualError(ValidateTokenizer("mecab"), "unsupported tokenizer: mecab, expected `whitespace` or `ptblike`")
+	suite.Nil(ValidateSplitter(SplitterNewline))
+	suite.EqualError(ValidateSplitter("nltk"), "unsupported sentence splitter: nltk, expected `regex` or `newline`")
+}
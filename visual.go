@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	visualConfName = "visual.conf"
+	// spanDefault holds the drawing of the types without their own
+	spanDefault = "SPAN_DEFAULT"
+)
+
+// ReadConfSections returns the lines of every `[section]` of a brat configuration file, without
+// the comments and blank lines
+func ReadConfSections(r io.Reader) (map[string][]string, error) {
+	sections := make(map[string][]string)
+	section := ""
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+		case strings.HasPrefix(line, "["):
+			return sections, &ParseError{Path: readerName(r), Line: lineNo, Column: 1, Raw: scanner.Text(), Kind: ErrParseBadSection}
+		default:
+			sections[section] = append(sections[section], line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return sections, &ParseError{Path: readerName(r), Line: lineNo + 1, Kind: ErrParseBadFormat, Err: err}
+	}
+	return sections, nil
+}
+
+// confOptions splits the `key:value, key:value` options following the name of a line of a conf
+func confOptions(line string) (string, map[string]string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	options := make(map[string]string)
+	for _, option := range strings.Split(strings.Join(fields[1:], " "), ",") {
+		keyAndValue := strings.SplitN(strings.TrimSpace(option), ":", 2)
+		if len(keyAndValue) == 2 {
+			options[keyAndValue[0]] = keyAndValue[1]
+		}
+	}
+	return fields[0], options
+}
+
+// VisualConf holds the display names and colours of a brat `visual.conf`
+type VisualConf struct {
+	// Labels maps a type to its display names, the full name first and its abbreviations next
+	Labels map[string][]string
+	// Drawing maps a type to its drawing options, such as bgColor and fgColor
+	Drawing map[string]map[string]string
+}
+
+func ParseVisualConf(r io.Reader) (*VisualConf, error) {
+	sections, err := ReadConfSections(r)
+	if err != nil {
+		return nil, err
+	}
+
+	visual := &VisualConf{Labels: make(map[string][]string), Drawing: make(map[string]map[string]string)}
+	for _, line := range sections["labels"] {
+		names := strings.Split(line, "|")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		visual.Labels[names[0]] = names[1:]
+	}
+	for _, line := range sections["drawing"] {
+		name, options := confOptions(line)
+		visual.Drawing[name] = options
+	}
+	return visual, nil
+}
+
+// drawing returns the drawing option of the type, or the default one of the spans
+func (v *VisualConf) drawing(name, option string) string {
+	if value, ok := v.Drawing[name][option]; ok {
+		return value
+	}
+	return v.Drawing[spanDefault][option]
+}
+
+// SchemaLabel is an entity type of the converted dataset, with the way brat displays it
+type SchemaLabel struct {
+	Type        string   `json:"type"`
+	DisplayName string   `json:"display_name"`
+	ShortNames  []string `json:"short_names,omitempty"`
+	BgColor     string   `json:"bg_color,omitempty"`
+	FgColor     string   `json:"fg_color,omitempty"`
+}
+
+// LabelSchema is written by `--schema` next to the converted records
+type LabelSchema struct {
+	Labels []SchemaLabel `json:"labels"`
+}
+
+// NewLabelSchema lists the entity types in the order of `[entities]`, after they are mapped by the label map.
+// visual can be nil when the collection has no `visual.conf`.
+func NewLabelSchema(types []string, visual *VisualConf, labelMap *LabelMap, hierarchy map[string]string) *LabelSchema {
+	if visual == nil {
+		visual = &VisualConf{}
+	}
+	schema := &LabelSchema{Labels: []SchemaLabel{}}
+	seen := make(map[string]bool)
+
+	for _, name := range types {
+		mapped := name
+		if labelMap != nil {
+			var ok bool
+			if mapped, ok = labelMap.MapType(name, hierarchy); !ok {
+				continue
+			}
+		}
+		if seen[mapped] {
+			continue
+		}
+		seen[mapped] = true
+
+		// A renamed type is displayed with the names of the new type when visual.conf has them
+		source := name
+		if _, ok := visual.Labels[mapped]; ok {
+			source = mapped
+		}
+		label := SchemaLabel{Type: mapped, DisplayName: mapped, BgColor: visual.drawing(source, "bgColor"), FgColor: visual.drawing(source, "fgColor")}
+		if names := visual.Labels[source]; len(names) > 0 && source == mapped {
+			label.DisplayName = names[0]
+			label.ShortNames = names[1:]
+		}
+		schema.Labels = append(schema.Labels, label)
+	}
+	return schema
+}
+
+// labelStudioView is the labeling config of a Label Studio named entity project
+type labelStudioView struct {
+	XMLName xml.Name `xml:"View"`
+	Labels  struct {
+		Name   string             `xml:"name,attr"`
+		ToName string             `xml:"toName,attr"`
+		Labels []labelStudioLabel `xml:"Label"`
+	} `xml:"Labels"`
+	Text struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"Text"`
+}
+
+type labelStudioLabel struct {
+	Value      string `xml:"value,attr"`
+	Background string `xml:"background,attr,omitempty"`
+	Hint       string `xml:"hint,attr,omitempty"`
+}
+
+// LabelStudioConfig returns the schema as a Label Studio labeling config, the display names are shown as hints
+func (s *LabelSchema) LabelStudioConfig() (string, error) {
+	view := labelStudioView{}
+	view.Labels.Name = "label"
+	view.Labels.ToName = "text"
+	view.Text.Name = "text"
+	view.Text.Value = "$text"
+	for _, label := range s.Labels {
+		studioLabel := labelStudioLabel{Value: label.Type, Background: label.BgColor}
+		if label.DisplayName != label.Type {
+			studioLabel.Hint = label.DisplayName
+		}
+		view.Labels.Labels = append(view.Labels.Labels, studioLabel)
+	}
+
+	data, err := xml.MarshalIndent(view, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// writeSchema writes the label schema as a Label Studio config when schemaFile ends with .xml, as JSON otherwise
+func writeSchema(schemaFile string, schema *LabelSchema, mode OutputMode) error {
+	if strings.ToLower(filepath.Ext(schemaFile)) == ".xml" {
+		config, err := schema.LabelStudioConfig()
+		if err != nil {
+			return err
+		}
+		return writeOutput(schemaFile, config, mode)
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(schemaFile, string(data)+"\n", mode)
+}
+
+// VisualConf reads the `visual.conf` in the root of the collection, next to `--conf` for the documents given one
+// by one, it returns nil when there is none
+func (c *Collection) VisualConf() (*VisualConf, error) {
+	if c.NearestFile == nil {
+		return nil, nil
+	}
+	visualPath := c.NearestFile("", visualConfName)
+	if visualPath == "" {
+		return nil, nil
+	}
+	f, err := c.Open(visualPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseVisualConf(f)
+}
+
+// writeCollectionSchema writes the schema of the entity types of every conf used by the converted documents,
+// followed by the other types converted without a conf
+func writeCollectionSchema(opts Options, collection *Collection, confs, otherTypes []string, labelMap *LabelMap) error {
+	types := []string{}
+	hierarchy := make(map[string]string)
+	for _, confPath := range confs {
+		order, err := collection.EntityOrder(confPath)
+		if err != nil {
+			return err
+		}
+		entities, err := collection.Entities(confPath)
+		if err != nil {
+			return err
+		}
+		// The types that can't be annotated, marked with `!`, are not part of the schema
+		for _, name := range order {
+			if entities[name] && !containsString(types, name) {
+				types = append(types, name)
+			}
+		}
+
+		confHierarchy, err := collection.Hierarchy(confPath)
+		if err != nil {
+			return err
+		}
+		for child, parent := range confHierarchy {
+			hierarchy[child] = parent
+		}
+	}
+
+	for _, name := range otherTypes {
+		if !containsString(types, name) {
+			types = append(types, name)
+		}
+	}
+
+	visual, err := collection.VisualConf()
+	if err != nil {
+		return err
+	}
+	mode, err := opts.OutputMode()
+	if err != nil {
+		return err
+	}
+	return writeSchema(opts.Schema, NewLabelSchema(types, visual, labelMap, hierarchy), mode)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
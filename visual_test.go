@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type VisualSuite struct {
+	suite.Suite
+}
+
+func (suite *VisualSuite) TestParseVisualConf() {
+	f, err := os.Open("./testData/news/visual.conf")
+	suite.Nil(err)
+	defer f.Close()
+
+	visual, err := ParseVisualConf(f)
+	suite.Nil(err)
+	suite.Equal([]string{"Geo-political entity", "GPE"}, visual.Labels["GPE"])
+	suite.Equal(map[string]string{"bgColor": "#007000", "fgColor": "white"}, visual.Drawing["Money"])
+	suite.Equal("#FFD412", visual.drawing("GPE", "bgColor"))
+	suite.Equal("black", visual.drawing("GPE", "fgColor"))
+}
+
+func (suite *VisualSuite) TestNewLabelSchema() {
+	visual := &VisualConf{
+		Labels:  map[string][]string{"GPE": {"Geo-political entity", "GPE"}},
+		Drawing: map[string]map[string]string{"GPE": {"bgColor": "#FFD412"}, "Person": {"bgColor": "#FF821C"}},
+	}
+	labelMap := &LabelMap{Rename: map[string]string{"Person": "PER", "Location": "GPE"}, drop: map[string]bool{"Money": true}}
+
+	schema := NewLabelSchema([]string{"Person", "GPE", "Location", "Money"}, visual, labelMap, nil)
+	suite.Equal([]SchemaLabel{
+		{Type: "PER", DisplayName: "PER", BgColor: "#FF821C"},
+		{Type: "GPE", DisplayName: "Geo-political entity", ShortNames: []string{"GPE"}, BgColor: "#FFD412"},
+	}, schema.Labels)
+
+	config, err := schema.LabelStudioConfig()
+	suite.Nil(err)
+	suite.Equal(`<View>
+  <Labels name="label" toName="text">
+    <Label value="PER" background="#FF821C"></Label>
+    <Label value="GPE" background="#FFD412" hint="Geo-political entity"></Label>
+  </Labels>
+  <Text name="text" value="$text"></Text>
+</View>
+`, config)
+
+	suite.Equal([]SchemaLabel{{Type: "Person", DisplayName: "Person"}}, NewLabelSchema([]string{"Person"}, nil, nil, nil).Labels)
+}
+
+func (suite *VisualSuite) TestHandleMainSchema() {
+	dir, err := ioutil.TempDir("", "schema")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+
+	schemaFile := filepath.Join(dir, "schema.json")
+	err = handleMain(Options{FolderPath: "./testData/news", OutputFile: filepath.Join(dir, "out.jsonl"), Schema: schemaFile})
+	suite.Nil(err)
+
+	data, err := ioutil.ReadFile(schemaFile)
+	suite.Nil(err)
+	suite.Contains(string(data), `"display_name": "Geo-political entity"`)
+	suite.Contains(string(data), `"bg_color": "#007000"`)
+
+	// Without visual.conf the types are still listed
+	schemaFile = filepath.Join(dir, "nested.json")
+	err = handleMain(Options{FolderPath: "./testData/nested-conf", OutputFile: filepath.Join(dir, "nested.jsonl"), Schema: schemaFile})
+	suite.Nil(err)
+	data, err = ioutil.ReadFile(schemaFile)
+	suite.Nil(err)
+	suite.Contains(string(data), `"type": "Organization"`)
+}
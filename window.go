@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	WindowUnitChars  = "chars"
+	WindowUnitTokens = "tokens"
+
+	ErrWindowUnknownUnit = "unknown window unit: %s, expected `chars` or `tokens`"
+	ErrWindowBadStride   = "`--stride` should be between 1 and the window size"
+	ErrWindowBadSize     = "`--window` should be a positive number"
+)
+
+func ValidateWindow(size, stride int, unit string) error {
+	if size <= 0 {
+		return errors.New(ErrWindowBadSize)
+	}
+	if stride < 0 || stride > size {
+		return errors.New(ErrWindowBadStride)
+	}
+	if unit != WindowUnitChars && unit != WindowUnitTokens {
+		return fmt.Errorf(ErrWindowUnknownUnit, unit)
+	}
+	return nil
+}
+
+// WindowSpans returns the character spans of the windows of size units, starting every stride units.
+// A window is shrunk, or grown when an entity is longer than the window, so that no entity is cut.
+// The tokens are split by tokenizer.
+func WindowSpans(text string, entities []NumberAcharyaEntity, size, stride int, unit, tokenizer string) []Span {
+	if stride <= 0 {
+		stride = size
+	}
+
+	// units holds the character span of every unit of the budget
+	units := []Span{}
+	if unit == WindowUnitTokens {
+		units = Tokenize(text, tokenizer)
+	} else {
+		for i := range textChars(text) {
+			units = append(units, Span{i, i + 1})
+		}
+	}
+	if len(units) == 0 {
+		return []Span{}
+	}
+
+	windows := []Span{}
+	for first := 0; ; first += stride {
+		last := minInt(first+size, len(units)) - 1
+		window := Span{units[first].Begin, units[last].End}
+
+		// The window starts before the entities it would cut
+		for moved := true; moved; {
+			moved = false
+			for _, ent := range entities {
+				if ent.Entity.Begin < window.Begin && window.Begin < ent.Entity.End {
+					window.Begin = ent.Entity.Begin
+					moved = true
+				}
+			}
+		}
+		// and ends before them, or after them when they don't fit
+		grow := false
+		for moved := true; moved; {
+			moved = false
+			for _, ent := range entities {
+				if ent.Entity.Begin < window.End && window.End < ent.Entity.End {
+					if !grow && ent.Entity.Begin > window.Begin {
+						window.End = ent.Entity.Begin
+					} else {
+						grow = true
+						window.End = ent.Entity.End
+					}
+					moved = true
+				}
+			}
+		}
+
+		if len(windows) == 0 || windows[len(windows)-1] != window {
+			windows = append(windows, window)
+		}
+		if last == len(units)-1 {
+			return windows
+		}
+	}
+}
+
+// Windows splits the document into overlapping windows, with the entity offsets rebased on the window
+func (d *Document) Windows(size, stride int, unit, tokenizer string) []*Document {
+	chars := textChars(d.Data)
+	windowed := []*Document{}
+
+	for i, span := range WindowSpans(d.Data, d.Entities, size, stride, unit, tokenizer) {
+		meta := d.Meta
+		meta.SourceDoc = d.TxtPath
+		offset := span.Begin
+		if d.Meta.SourceOffset != nil {
+			offset += *d.Meta.SourceOffset
+		}
+		meta.SourceOffset = &offset
+		window := i
+		meta.Window = &window
+
+		doc := &Document{AnnPath: d.AnnPath, TxtPath: d.TxtPath, Data: string(chars[span.Begin:span.End]), Entities: []NumberAcharyaEntity{}, Meta: meta,
+			Tokenizer: d.Tokenizer, Splitter: d.Splitter}
+		for _, ent := range d.Entities {
+			if ent.Entity.Begin >= span.Begin && ent.Entity.End <= span.End {
+				ent.Entity.Begin -= span.Begin
+				ent.Entity.End -= span.Begin
+				doc.Entities = append(doc.Entities, ent)
+			}
+		}
+		windowed = append(windowed, doc)
+	}
+	return windowed
+}
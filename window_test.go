@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WindowSuite struct {
+	suite.Suite
+}
+
+func (suite *WindowSuite) TestWindowSpans() {
+	text := "aaaa bbbb cccc dddd"
+	suite.Equal([]Span{{0, 8}, {4, 12}, {8, 16}, {12, 19}}, WindowSpans(text, nil, 8, 4, WindowUnitChars, TokenizerWhitespace))
+	suite.Equal([]Span{{0, 9}, {5, 14}, {10, 19}}, WindowSpans(text, nil, 2, 1, WindowUnitTokens, TokenizerWhitespace))
+	suite.Equal([]Span{{0, 19}}, WindowSpans(text, nil, 10, 10, WindowUnitTokens, TokenizerWhitespace))
+
+	// "bbbb cccc" is never cut, windows end before it or start at its beginning
+	entities := []NumberAcharyaEntity{{1, AcharyaEntity{5, 14, "X"}}}
+	suite.Equal([]Span{{0, 5}, {4, 5}, {5, 16}, {5, 19}}, WindowSpans(text, entities, 8, 4, WindowUnitChars, TokenizerWhitespace))
+
+	// An entity longer than the window grows it
+	suite.Equal(Span{0, 14}, WindowSpans(text, []NumberAcharyaEntity{{1, AcharyaEntity{0, 14, "X"}}}, 1, 1, WindowUnitTokens, TokenizerWhitespace)[0])
+}
+
+func (suite *WindowSuite) TestDocumentWindows() {
+	offset := 100
+	doc := &Document{
+		AnnPath:  "doc.ann",
+		TxtPath:  "doc.txt",
+		Data:     "Sony is in Tokyo and Paris",
+		Entities: []NumberAcharyaEntity{{1, AcharyaEntity{0, 4, "Organization"}}, {2, AcharyaEntity{11, 16, "GPE"}}, {3, AcharyaEntity{21, 26, "GPE"}}},
+		Meta:     RecordMeta{Conf: "annotation.conf", SourceOffset: &offset},
+	}
+
+	windows := doc.Windows(3, 2, WindowUnitTokens, TokenizerWhitespace)
+	suite.Len(windows, 3)
+	suite.Equal("in Tokyo and", windows[1].Data)
+	suite.Equal([]NumberAcharyaEntity{{2, AcharyaEntity{3, 8, "GPE"}}}, windows[1].Entities)
+	suite.Equal(108, *windows[1].Meta.SourceOffset)
+	suite.Equal(1, *windows[1].Meta.Window)
+
+	record, err := windows[0].Acharya()
+	suite.Nil(err)
+	suite.Equal("{\"Data\":\"Sony is in\",\"Entities\":[[0,4,\"Organization\"]],"+
+		"\"Meta\":{\"conf\":\"annotation.conf\",\"source_doc\":\"doc.txt\",\"source_offset\":100,\"window\":0}}\n", record)
+}
+
+func (suite *WindowSuite) TestBadSpanIsReported() {
+	dir, err := ioutil.TempDir("", "window")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "annotation.conf"), []byte("[entities]\nPerson\n[relations]\n[events]\n[attributes]\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("John."), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.ann"), []byte("T1\tPerson 0 90\tJohn\n"), 0600))
+
+	report := filepath.Join(dir, "report.json")
+	err = handleMain(Options{FolderPath: dir, OutputFile: filepath.Join(dir, "out.jsonl"), Window: 2, WindowUnit: WindowUnitChars, KeepGoing: true, ErrorReport: report})
+	var failed *DocumentsFailedError
+	suite.True(errors.As(err, &failed))
+	data, err := ioutil.ReadFile(report)
+	suite.Nil(err)
+	suite.Contains(string(data), CodeBadSpan)
+
+	doc := &Document{AnnPath: "a.ann", Data: "John.", Entities: []NumberAcharyaEntity{{1, AcharyaEntity{0, 90, "Person"}}}}
+	suite.True(errors.Is(doc.CheckSpans(), ErrParseBadSpan))
+	doc.Entities[0].Entity.End = 4
+	suite.Nil(doc.CheckSpans())
+}
+
+func (suite *WindowSuite) TestWindowsOfSegments() {
+	dir, err := ioutil.TempDir("", "window")
+	suite.Nil(err)
+	defer os.RemoveAll(dir)
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "annotation.conf"), []byte("[entities]\nPerson\n[relations]\n[events]\n[attributes]\n"), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("John left now. Mary stayed in."), 0600))
+	suite.Nil(ioutil.WriteFile(filepath.Join(dir, "a.ann"), []byte("T1\tPerson 0 4\tJohn\nT2\tPerson 15 19\tMary\n"), 0600))
+
+	output := filepath.Join(dir, "out.jsonl")
+	suite.Nil(handleMain(Options{FolderPath: dir, OutputFile: output, Segment: SegmentSentence, Crossing: CrossingDrop, Splitter: SplitterRegex,
+		Window: 2, WindowUnit: WindowUnitTokens, Tokenizer: TokenizerWhitespace}))
+	data, err := ioutil.ReadFile(output)
+	suite.Nil(err)
+	windows := []int{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		record := struct{ Meta RecordMeta }{}
+		suite.Nil(json.Unmarshal([]byte(line), &record))
+		windows = append(windows, *record.Meta.Window)
+	}
+	// The windows are numbered across the two sentences of the document
+	suite.Equal([]int{0, 1, 2, 3}, windows)
+}
+
+func (suite *WindowSuite) TestValidateWindow() {
+	suite.Nil(ValidateWindow(512, 0, WindowUnitTokens))
+	suite.EqualError(ValidateWindow(0, 0, WindowUnitChars), ErrWindowBadSize)
+	suite.EqualError(ValidateWindow(10, 20, WindowUnitChars), ErrWindowBadStride)
+	suite.EqualError(ValidateWindow(10, 5, "words"), "unknown window unit: words, expected `chars` or `tokens`")
+}